@@ -0,0 +1,228 @@
+// Package awssession builds the aws session of the commands from the session flags: profile, role to assume and mfa.
+// Credentials obtained with an mfa code are cached under ~/.aws, shared by every command.
+package awssession
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// DefaultSessionName is the role session name of role-arn. It shows in CloudTrail.
+const DefaultSessionName = "aws-machete"
+
+// Options shape the session every aws client of a command is built from.
+type Options struct {
+	profile    string
+	roleArn    string
+	externalId string
+	mfaSerial  string
+	duration   time.Duration
+}
+
+// AddFlags registers the session flags as persistent flags of the root command.
+func AddFlags(flags *pflag.FlagSet) {
+	flags.String("profile", "", "Named profile of the shared aws config and credentials files to use.")
+	flags.String("role-arn", "", "Role to assume with the profile's credentials.")
+	flags.String("external-id", "", "External id to assume role-arn with.")
+	flags.String("mfa-serial", "", "Serial number or arn of the mfa device. The code is prompted for, and the credentials cached until they expire.")
+	flags.Duration("duration", time.Hour, "Lifetime of the credentials obtained through role-arn or mfa-serial.")
+}
+
+// ReadOptions reads the session flags. Invalid values are returned as errstrings.
+func ReadOptions(localViper *viper.Viper) (*Options, []string) {
+	var errstrings []string
+	options := &Options{
+		profile:    localViper.GetString("profile"),
+		roleArn:    localViper.GetString("role-arn"),
+		externalId: localViper.GetString("external-id"),
+		mfaSerial:  localViper.GetString("mfa-serial"),
+		duration:   localViper.GetDuration("duration"),
+	}
+
+	if options.externalId != "" && options.roleArn == "" {
+		errstrings = append(errstrings, "Please specify role-arn to use external-id with.")
+	}
+	if options.duration < 15*time.Minute || options.duration > 12*time.Hour {
+		errstrings = append(errstrings, fmt.Sprintf("Invalid duration %v. It has to be between 15m and 12h.", options.duration))
+	}
+
+	return options, errstrings
+}
+
+// New builds the session of a command: the profile's credentials, or the default chain without a profile,
+// then the role assumed with them, or a session token with an mfa code only.
+// Credentials obtained with an mfa code are cached, so the next invocations don't prompt again until they expire.
+func New(options *Options) (*session.Session, error) {
+	sess, sessErr := session.NewSessionWithOptions(session.Options{
+		Profile:                 options.profile,
+		SharedConfigState:       session.SharedConfigEnable,
+		AssumeRoleTokenProvider: stscreds.StdinTokenProvider, // profiles with a role and an mfa_serial of their own
+	})
+	if sessErr != nil {
+		return nil, sessErr
+	}
+
+	var provider credentials.Provider
+	switch {
+	case options.roleArn != "":
+		assumeRoleProvider := &stscreds.AssumeRoleProvider{
+			Client:          sts.New(sess),
+			RoleARN:         options.roleArn,
+			RoleSessionName: DefaultSessionName,
+			Duration:        options.duration,
+		}
+		if options.externalId != "" {
+			assumeRoleProvider.ExternalID = aws.String(options.externalId)
+		}
+		if options.mfaSerial != "" {
+			assumeRoleProvider.SerialNumber = aws.String(options.mfaSerial)
+			assumeRoleProvider.TokenProvider = promptMfaCode(options.mfaSerial)
+		}
+		provider = assumeRoleProvider
+	case options.mfaSerial != "":
+		provider = &sessionTokenProvider{
+			client:        sts.New(sess),
+			serialNumber:  options.mfaSerial,
+			duration:      options.duration,
+			tokenProvider: promptMfaCode(options.mfaSerial),
+		}
+	default:
+		return sess, nil
+	}
+
+	if options.mfaSerial != "" {
+		provider = &cachedProvider{
+			path:     credentialsCachePath(options),
+			provider: provider,
+		}
+	}
+	return sess.Copy(&aws.Config{
+		Credentials: credentials.NewCredentials(provider),
+	}), nil
+}
+
+// promptMfaCode returns a token provider asking for the current code of an mfa device.
+func promptMfaCode(serialNumber string) func() (string, error) {
+	return func() (string, error) {
+		fmt.Fprintf(os.Stderr, "MFA code for %v: ", serialNumber)
+		var code string
+		if _, scanErr := fmt.Scanln(&code); scanErr != nil {
+			return "", errors.New(fmt.Sprintf("Unable to read the mfa code: %v", scanErr))
+		}
+		return strings.TrimSpace(code), nil
+	}
+}
+
+// sessionTokenProvider gets temporary credentials of the caller with an mfa code, for mfa without a role.
+type sessionTokenProvider struct {
+	credentials.Expiry
+	client        stsiface.STSAPI
+	serialNumber  string
+	duration      time.Duration
+	tokenProvider func() (string, error)
+}
+
+func (p *sessionTokenProvider) Retrieve() (credentials.Value, error) {
+	code, codeErr := p.tokenProvider()
+	if codeErr != nil {
+		return credentials.Value{}, codeErr
+	}
+	out, tokenErr := p.client.GetSessionToken(&sts.GetSessionTokenInput{
+		DurationSeconds: aws.Int64(int64(p.duration / time.Second)),
+		SerialNumber:    aws.String(p.serialNumber),
+		TokenCode:       aws.String(code),
+	})
+	if tokenErr != nil {
+		return credentials.Value{}, tokenErr
+	}
+
+	p.SetExpiration(aws.TimeValue(out.Credentials.Expiration), 0)
+	return credentials.Value{
+		AccessKeyID:     aws.StringValue(out.Credentials.AccessKeyId),
+		SecretAccessKey: aws.StringValue(out.Credentials.SecretAccessKey),
+		SessionToken:    aws.StringValue(out.Credentials.SessionToken),
+		ProviderName:    "SessionTokenProvider",
+	}, nil
+}
+
+// credentialsCacheWindow is how long before they expire cached credentials are no longer used.
+const credentialsCacheWindow = time.Minute
+
+// cachedCredentials is the content of a credentials cache file.
+type cachedCredentials struct {
+	AccessKeyId     string    `json:"AccessKeyId"`
+	SecretAccessKey string    `json:"SecretAccessKey"`
+	SessionToken    string    `json:"SessionToken"`
+	Expiration      time.Time `json:"Expiration"`
+}
+
+// cachedProvider keeps the credentials of a provider in a file until they expire. provider has to be a credentials.Expirer.
+type cachedProvider struct {
+	credentials.Expiry
+	path     string
+	provider credentials.Provider
+}
+
+func (p *cachedProvider) Retrieve() (credentials.Value, error) {
+	if content, readErr := ioutil.ReadFile(p.path); readErr == nil {
+		cached := &cachedCredentials{}
+		if json.Unmarshal(content, cached) == nil && time.Until(cached.Expiration) > credentialsCacheWindow {
+			p.SetExpiration(cached.Expiration, credentialsCacheWindow)
+			return credentials.Value{
+				AccessKeyID:     cached.AccessKeyId,
+				SecretAccessKey: cached.SecretAccessKey,
+				SessionToken:    cached.SessionToken,
+				ProviderName:    "CachedProvider",
+			}, nil
+		}
+	}
+
+	value, retrieveErr := p.provider.Retrieve()
+	if retrieveErr != nil {
+		return value, retrieveErr
+	}
+	expiration := p.provider.(credentials.Expirer).ExpiresAt()
+	p.SetExpiration(expiration, credentialsCacheWindow)
+
+	// A cache that cannot be written only means prompting again next time.
+	content, _ := json.Marshal(&cachedCredentials{
+		AccessKeyId:     value.AccessKeyID,
+		SecretAccessKey: value.SecretAccessKey,
+		SessionToken:    value.SessionToken,
+		Expiration:      expiration,
+	})
+	if dirErr := os.MkdirAll(filepath.Dir(p.path), 0700); dirErr != nil {
+		fmt.Printf("Unable to cache credentials: %v\n", dirErr)
+	} else if writeErr := ioutil.WriteFile(p.path, content, 0600); writeErr != nil {
+		fmt.Printf("Unable to cache credentials: %v\n", writeErr)
+	}
+	return value, nil
+}
+
+// credentialsCachePath returns the cache file of the credentials of a set of session options, under ~/.aws like the aws cli's.
+func credentialsCachePath(options *Options) string {
+	key := strings.Join([]string{options.profile, options.roleArn, options.externalId, options.mfaSerial, options.duration.String()}, "|")
+	hash := sha256.Sum256([]byte(key))
+	home, homeErr := os.UserHomeDir()
+	if homeErr != nil {
+		home = os.TempDir()
+	}
+	return filepath.Join(home, ".aws", "machete", "cache", hex.EncodeToString(hash[:16])+".json")
+}
@@ -0,0 +1,126 @@
+package awssession
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"github.com/spf13/viper"
+)
+
+// countingProvider hands out new credentials valid for an hour on every call.
+type countingProvider struct {
+	credentials.Expiry
+	calls int
+}
+
+func (p *countingProvider) Retrieve() (credentials.Value, error) {
+	p.calls = p.calls + 1
+	p.SetExpiration(time.Now().Add(time.Hour), 0)
+	return credentials.Value{AccessKeyID: "key", SecretAccessKey: "secret", SessionToken: "token"}, nil
+}
+
+type mockSTS struct {
+	stsiface.STSAPI
+	input *sts.GetSessionTokenInput
+}
+
+func (m *mockSTS) GetSessionToken(input *sts.GetSessionTokenInput) (*sts.GetSessionTokenOutput, error) {
+	m.input = input
+	return &sts.GetSessionTokenOutput{
+		Credentials: &sts.Credentials{
+			AccessKeyId:     aws.String("key"),
+			SecretAccessKey: aws.String("secret"),
+			SessionToken:    aws.String("token"),
+			Expiration:      aws.Time(time.Now().Add(time.Hour)),
+		},
+	}, nil
+}
+
+func TestReadOptions(t *testing.T) {
+	cases := []struct {
+		values map[string]interface{}
+		valid  bool
+	}{
+		{map[string]interface{}{"duration": time.Hour}, true},
+		{map[string]interface{}{"duration": time.Hour, "role-arn": "arn:aws:iam::111111111111:role/machete", "external-id": "secret"}, true},
+		{map[string]interface{}{"duration": time.Hour, "external-id": "secret"}, false},
+		{map[string]interface{}{"duration": time.Minute}, false},
+		{map[string]interface{}{"duration": 13 * time.Hour}, false},
+	}
+
+	for i, c := range cases {
+		// arrange
+		localViper := viper.New()
+		for key, value := range c.values {
+			localViper.Set(key, value)
+		}
+
+		// act
+		_, errstrings := ReadOptions(localViper)
+
+		// assert
+		if (len(errstrings) == 0) != c.valid {
+			t.Errorf("Case %v: expected valid %v, got %v", i, c.valid, errstrings)
+		}
+	}
+}
+
+func TestCachedProvider_ReusesCachedCredentials(t *testing.T) {
+	// arrange
+	cachePath := filepath.Join(t.TempDir(), "cache", "credentials.json")
+	provider := &countingProvider{}
+
+	// act
+	first, firstErr := (&cachedProvider{path: cachePath, provider: provider}).Retrieve()
+	second, secondErr := (&cachedProvider{path: cachePath, provider: provider}).Retrieve()
+
+	// assert
+	if firstErr != nil || secondErr != nil {
+		t.Fatal(firstErr, secondErr)
+	}
+	if provider.calls != 1 {
+		t.Errorf("The second invocation should use the cached credentials. %v calls", provider.calls)
+	}
+	if second.AccessKeyID != first.AccessKeyID || second.SessionToken != "token" {
+		t.Errorf("Cached credentials incorrect. %#v", second)
+	}
+}
+
+func TestSessionTokenProvider(t *testing.T) {
+	// arrange
+	client := &mockSTS{}
+	provider := &sessionTokenProvider{
+		client:        client,
+		serialNumber:  "arn:aws:iam::111111111111:mfa/user",
+		duration:      2 * time.Hour,
+		tokenProvider: func() (string, error) { return "123456", nil },
+	}
+
+	// act
+	value, err := provider.Retrieve()
+
+	// assert
+	if err != nil || value.SessionToken != "token" {
+		t.Fatal(value, err)
+	}
+	if aws.StringValue(client.input.TokenCode) != "123456" || aws.Int64Value(client.input.DurationSeconds) != 7200 {
+		t.Errorf("Session token requested incorrectly. %#v", client.input)
+	}
+	if provider.IsExpired() {
+		t.Error("Credentials should not be expired.")
+	}
+}
+
+func TestCredentialsCachePath(t *testing.T) {
+	base := &Options{mfaSerial: "arn:aws:iam::111111111111:mfa/user", duration: time.Hour}
+	other := &Options{mfaSerial: "arn:aws:iam::111111111111:mfa/user", duration: time.Hour, profile: "prod"}
+
+	if credentialsCachePath(base) == credentialsCachePath(other) {
+		t.Error("Different session options should not share cached credentials.")
+	}
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+
+	"aws-machete/src/awssession"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultSessionName is the role session name of accounts that don't set one. It shows in CloudTrail.
+const defaultSessionName = awssession.DefaultSessionName
+
+var accountIdPattern = regexp.MustCompile(`^\d{12}$`)
+
+// account is an entry of the accounts file. Commands going through every region fan out to each account
+// through its role instead of the session's account.
+type account struct {
+	AccountId   string   `yaml:"account-id"`
+	RoleArn     string   `yaml:"role-arn"`
+	ExternalId  string   `yaml:"external-id"`
+	SessionName string   `yaml:"session-name"`
+	Regions     []string `yaml:"regions"` // overrides the regions selected for every account
+}
+
+// readAccounts reads and validates an accounts file, a yaml list of accounts.
+func readAccounts(accountsFile string) ([]*account, error) {
+	content, readErr := ioutil.ReadFile(accountsFile)
+	if readErr != nil {
+		return nil, readErr
+	}
+	accounts := make([]*account, 0)
+	if parseErr := yaml.Unmarshal(content, &accounts); parseErr != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to parse accounts file %v: %v", accountsFile, parseErr))
+	}
+	if len(accounts) == 0 {
+		return nil, errors.New(fmt.Sprintf("No accounts in accounts file %v.", accountsFile))
+	}
+
+	seen := make(map[string]bool)
+	for i, acct := range accounts {
+		if !accountIdPattern.MatchString(acct.AccountId) {
+			return nil, errors.New(fmt.Sprintf("Account %v: invalid account-id %#v.", i+1, acct.AccountId))
+		}
+		if seen[acct.AccountId] {
+			return nil, errors.New(fmt.Sprintf("Account %v is listed more than once.", acct.AccountId))
+		}
+		seen[acct.AccountId] = true
+
+		roleAccountId, arnErr := getAccountIdFromArn(aws.String(acct.RoleArn))
+		if arnErr != nil {
+			return nil, errors.New(fmt.Sprintf("Account %v: %v", acct.AccountId, arnErr))
+		}
+		if roleAccountId != acct.AccountId {
+			return nil, errors.New(fmt.Sprintf("Account %v: role %v belongs to account %v.", acct.AccountId, acct.RoleArn, roleAccountId))
+		}
+		if acct.SessionName == "" {
+			acct.SessionName = defaultSessionName
+		}
+	}
+	return accounts, nil
+}
+
+// assumeRole returns a copy of a session that acts as the role of an account.
+// The role is assumed on first use and again before the credentials expire.
+func assumeRole(sess *session.Session, acct *account) *session.Session {
+	return sess.Copy(&aws.Config{
+		Credentials: stscreds.NewCredentials(sess, acct.RoleArn, func(provider *stscreds.AssumeRoleProvider) {
+			provider.RoleSessionName = acct.SessionName
+			if acct.ExternalId != "" {
+				provider.ExternalID = aws.String(acct.ExternalId)
+			}
+		}),
+	})
+}
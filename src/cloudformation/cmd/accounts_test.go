@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeAccountsFile(t *testing.T, content string) string {
+	accountsFile := filepath.Join(t.TempDir(), "accounts.yml")
+	if err := ioutil.WriteFile(accountsFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return accountsFile
+}
+
+func TestReadAccounts(t *testing.T) {
+	// arrange
+	accountsFile := writeAccountsFile(t, `
+- account-id: "111111111111"
+  role-arn: arn:aws:iam::111111111111:role/machete
+  external-id: secret
+  regions: [us-east-1, eu-west-1]
+- account-id: "222222222222"
+  role-arn: arn:aws-us-gov:iam::222222222222:role/machete
+  session-name: nightly-cleanup
+`)
+
+	// act
+	accounts, err := readAccounts(accountsFile)
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 2 || len(accounts[0].Regions) != 2 || accounts[0].ExternalId != "secret" {
+		t.Errorf("Accounts not read. %#v", accounts)
+	}
+	if accounts[0].SessionName != defaultSessionName || accounts[1].SessionName != "nightly-cleanup" {
+		t.Errorf("Session names incorrect. %v, %v", accounts[0].SessionName, accounts[1].SessionName)
+	}
+}
+
+func TestReadAccounts_Invalid(t *testing.T) {
+	cases := map[string]string{
+		"invalid account-id": `
+- account-id: "1234"
+  role-arn: arn:aws:iam::1234:role/machete
+`,
+		"belongs to account": `
+- account-id: "111111111111"
+  role-arn: arn:aws:iam::222222222222:role/machete
+`,
+		"Invalid arn": `
+- account-id: "111111111111"
+  role-arn: machete
+`,
+		"more than once": `
+- account-id: "111111111111"
+  role-arn: arn:aws:iam::111111111111:role/machete
+- account-id: "111111111111"
+  role-arn: arn:aws:iam::111111111111:role/other
+`,
+		"No accounts": `[]`,
+	}
+
+	for expected, content := range cases {
+		// act
+		_, err := readAccounts(writeAccountsFile(t, content))
+
+		// assert
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected an error containing %#v, got %v", expected, err)
+		}
+	}
+}
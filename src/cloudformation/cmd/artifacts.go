@@ -0,0 +1,301 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// artifact location formats.
+const (
+	s3Uri     = iota // s3://bucket/key
+	s3Url            // https url of the object
+	bucketKey        // mapping with the bucket and key properties
+)
+
+// artifactProperty describes a resource property that may point at a local artifact.
+type artifactProperty struct {
+	path      string // dot separated property path
+	format    int
+	zip       bool   // zip the artifact unless it already is an archive
+	bucketKey string // bucket property name for the bucketKey format
+	objectKey string // key property name for the bucketKey format
+}
+
+var artifactProperties = map[string][]artifactProperty{
+	"AWS::Serverless::Function":                 {{path: "CodeUri", format: s3Uri, zip: true}},
+	"AWS::Serverless::LayerVersion":             {{path: "ContentUri", format: s3Uri, zip: true}},
+	"AWS::Serverless::Api":                      {{path: "DefinitionUri", format: s3Uri}},
+	"AWS::Serverless::HttpApi":                  {{path: "DefinitionUri", format: s3Uri}},
+	"AWS::Serverless::StateMachine":             {{path: "DefinitionUri", format: s3Uri}},
+	"AWS::Lambda::Function":                     {{path: "Code", format: bucketKey, zip: true, bucketKey: "S3Bucket", objectKey: "S3Key"}},
+	"AWS::Lambda::LayerVersion":                 {{path: "Content", format: bucketKey, zip: true, bucketKey: "S3Bucket", objectKey: "S3Key"}},
+	"AWS::ApiGateway::RestApi":                  {{path: "BodyS3Location", format: bucketKey, bucketKey: "Bucket", objectKey: "Key"}},
+	"AWS::StepFunctions::StateMachine":          {{path: "DefinitionS3Location", format: bucketKey, bucketKey: "Bucket", objectKey: "Key"}},
+	"AWS::ElasticBeanstalk::ApplicationVersion": {{path: "SourceBundle", format: bucketKey, zip: true, bucketKey: "S3Bucket", objectKey: "S3Key"}},
+	"AWS::Glue::Job":                            {{path: "Command.ScriptLocation", format: s3Uri}},
+	"AWS::AppSync::GraphQLSchema":               {{path: "DefinitionS3Location", format: s3Uri}},
+	nestedStackResourceType:                     {{path: "TemplateURL", format: s3Url}},
+}
+
+// templatePackager uploads local artifacts referenced by a template and rewrites the references to point at s3.
+type templatePackager struct {
+	ctx             context.Context
+	artifactManager artifactManagement
+	bucket          string
+}
+
+// packageTemplate returns the template at templatePath with every local artifact replaced by its s3 location.
+func (p *templatePackager) packageTemplate(templatePath string) (string, error) {
+	buffer, readErr := ioutil.ReadFile(templatePath)
+	if readErr != nil {
+		return "", readErr
+	}
+
+	var document yaml.Node
+	if parseErr := yaml.Unmarshal(buffer, &document); parseErr != nil {
+		return "", parseErr
+	}
+	if len(document.Content) == 0 {
+		return "", errors.New(fmt.Sprintf("Template %v is empty.", templatePath))
+	}
+	root := document.Content[0]
+	baseDir := filepath.Dir(templatePath)
+
+	// Resource properties
+	if resources := mappingValue(root, "Resources"); resources != nil {
+		for i := 0; i+1 < len(resources.Content); i += 2 {
+			resource := resources.Content[i+1]
+			resourceType := mappingValue(resource, "Type")
+			if resourceType == nil {
+				continue
+			}
+			for _, property := range artifactProperties[resourceType.Value] {
+				node := propertyValue(mappingValue(resource, "Properties"), property.path)
+				if err := p.packageProperty(node, property, baseDir); err != nil {
+					return "", errors.New(fmt.Sprintf("Unable to package %v.%v: %v", resources.Content[i].Value, property.path, err))
+				}
+			}
+		}
+	}
+
+	// AWS::Include snippets can be anywhere in the template.
+	if err := p.packageIncludes(root, baseDir); err != nil {
+		return "", err
+	}
+
+	out := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(out)
+	encoder.SetIndent(2)
+	if encodeErr := encoder.Encode(&document); encodeErr != nil {
+		return "", encodeErr
+	}
+	encoder.Close()
+	return out.String(), nil
+}
+
+func (p *templatePackager) packageIncludes(node *yaml.Node, baseDir string) error {
+	if node == nil {
+		return nil
+	}
+
+	var transform *yaml.Node
+	if node.Tag == "!Transform" {
+		transform = node
+	} else {
+		transform = mappingValue(node, "Fn::Transform")
+	}
+	if transform != nil {
+		if name := mappingValue(transform, "Name"); name != nil && name.Value == "AWS::Include" {
+			location := mappingValue(mappingValue(transform, "Parameters"), "Location")
+			property := artifactProperty{path: "Location", format: s3Uri}
+			if err := p.packageProperty(location, property, baseDir); err != nil {
+				return errors.New(fmt.Sprintf("Unable to package AWS::Include: %v", err))
+			}
+		}
+	}
+
+	for _, child := range node.Content {
+		if err := p.packageIncludes(child, baseDir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// packageProperty uploads the artifact a property points at and rewrites the property in place.
+// Properties that are missing, intrinsic functions or already remote are left untouched.
+func (p *templatePackager) packageProperty(node *yaml.Node, property artifactProperty, baseDir string) error {
+	if node == nil || node.Kind != yaml.ScalarNode || node.Tag != "!!str" || !isLocalArtifact(node.Value) {
+		return nil
+	}
+
+	artifactPath := node.Value
+	if !filepath.IsAbs(artifactPath) {
+		artifactPath = filepath.Join(baseDir, artifactPath)
+	}
+
+	content, extension, readErr := p.readArtifact(artifactPath, property)
+	if readErr != nil {
+		return readErr
+	}
+
+	key := contentHashKey(content, extension)
+	url, uploadErr := p.artifactManager.upload(p.ctx, p.bucket, key, content)
+	if uploadErr != nil {
+		return uploadErr
+	}
+	fmt.Printf("Uploaded %v to s3://%v/%v\n", artifactPath, p.bucket, key)
+
+	switch property.format {
+	case s3Url:
+		setScalar(node, url)
+	case s3Uri:
+		setScalar(node, "s3://"+p.bucket+"/"+key)
+	case bucketKey:
+		node.Kind = yaml.MappingNode
+		node.Tag = "!!map"
+		node.Value = ""
+		node.Style = 0
+		node.Content = []*yaml.Node{
+			scalarNode(property.bucketKey), scalarNode(p.bucket),
+			scalarNode(property.objectKey), scalarNode(key),
+		}
+	}
+	return nil
+}
+
+// readArtifact returns the bytes to upload for an artifact and the extension of its key.
+// Nested stack templates are packaged recursively.
+func (p *templatePackager) readArtifact(artifactPath string, property artifactProperty) ([]byte, string, error) {
+	info, statErr := os.Stat(artifactPath)
+	if statErr != nil {
+		return nil, "", statErr
+	}
+
+	if info.IsDir() {
+		if !property.zip {
+			return nil, "", errors.New(fmt.Sprintf("%v is a directory.", artifactPath))
+		}
+		content, zipErr := zipDirectory(artifactPath)
+		return content, ".zip", zipErr
+	}
+
+	if property.format == s3Url {
+		packaged, packageErr := p.packageTemplate(artifactPath)
+		return []byte(packaged), ".template", packageErr
+	}
+
+	extension := strings.ToLower(filepath.Ext(artifactPath))
+	if property.zip && extension != ".zip" && extension != ".jar" {
+		content, zipErr := zipFiles(filepath.Dir(artifactPath), []string{artifactPath})
+		return content, ".zip", zipErr
+	}
+
+	content, readErr := ioutil.ReadFile(artifactPath)
+	return content, extension, readErr
+}
+
+func isLocalArtifact(value string) bool {
+	return value != "" &&
+		!strings.HasPrefix(value, "s3://") &&
+		!strings.HasPrefix(value, "http://") &&
+		!strings.HasPrefix(value, "https://")
+}
+
+func zipDirectory(dir string) ([]byte, error) {
+	files := make([]string, 0)
+	walkErr := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if walkErr != nil {
+		return nil, walkErr
+	}
+	return zipFiles(dir, files)
+}
+
+// zipFiles builds a zip archive with entries relative to baseDir.
+// Entries are sorted and timestamps fixed so the same content always hashes to the same key.
+func zipFiles(baseDir string, files []string) ([]byte, error) {
+	sort.Strings(files)
+	buffer := &bytes.Buffer{}
+	archive := zip.NewWriter(buffer)
+	for _, file := range files {
+		info, statErr := os.Stat(file)
+		if statErr != nil {
+			return nil, statErr
+		}
+		name, relErr := filepath.Rel(baseDir, file)
+		if relErr != nil {
+			return nil, relErr
+		}
+		header := &zip.FileHeader{
+			Name:     filepath.ToSlash(name),
+			Method:   zip.Deflate,
+			Modified: time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC),
+		}
+		header.SetMode(info.Mode())
+		writer, headerErr := archive.CreateHeader(header)
+		if headerErr != nil {
+			return nil, headerErr
+		}
+		content, readErr := ioutil.ReadFile(file)
+		if readErr != nil {
+			return nil, readErr
+		}
+		if _, writeErr := writer.Write(content); writeErr != nil {
+			return nil, writeErr
+		}
+	}
+	if closeErr := archive.Close(); closeErr != nil {
+		return nil, closeErr
+	}
+	return buffer.Bytes(), nil
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func propertyValue(node *yaml.Node, path string) *yaml.Node {
+	for _, key := range strings.Split(path, ".") {
+		node = mappingValue(node, key)
+	}
+	return node
+}
+
+func setScalar(node *yaml.Node, value string) {
+	node.Kind = yaml.ScalarNode
+	node.Tag = "!!str"
+	node.Value = value
+	node.Style = 0
+	node.Content = nil
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func writeTestFile(t *testing.T, path string, content string) {
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPackageTemplate_RewritesLocalArtifacts(t *testing.T) {
+	// arrange
+	dir, _ := ioutil.TempDir("", "package")
+	defer os.RemoveAll(dir)
+	writeTestFile(t, filepath.Join(dir, "src", "index.js"), "exports.handler = () => {}")
+	writeTestFile(t, filepath.Join(dir, "child.yml"), "Resources:\n  Queue:\n    Type: AWS::SQS::Queue\n")
+	writeTestFile(t, filepath.Join(dir, "snippet.yml"), "Description: included\n")
+	templatePath := filepath.Join(dir, "template.yml")
+	writeTestFile(t, templatePath, `Resources:
+  Function:
+    Type: AWS::Lambda::Function
+    Properties:
+      Code: ./src
+      Role: !GetAtt Role.Arn
+  InlineFunction:
+    Type: AWS::Lambda::Function
+    Properties:
+      Code:
+        ZipFile: "exports.handler = () => {}"
+  RemoteFunction:
+    Type: AWS::Serverless::Function
+    Properties:
+      CodeUri: s3://other/code.zip
+  Child:
+    Type: AWS::CloudFormation::Stack
+    Properties:
+      TemplateURL: child.yml
+  Api:
+    Type: AWS::ApiGateway::RestApi
+    Properties:
+      Fn::Transform:
+        Name: AWS::Include
+        Parameters:
+          Location: snippet.yml
+`)
+	artifactManager := &mockArtifactManager{}
+	packager := &templatePackager{
+		ctx:             context.Background(),
+		artifactManager: artifactManager,
+		bucket:          "bucket",
+	}
+
+	// act
+	packaged, err := packager.packageTemplate(templatePath)
+
+	// assert
+	if err != nil {
+		t.Fatalf("packageTemplate should not fail. %v", err)
+	}
+	if len(artifactManager.uploads) != 3 {
+		t.Errorf("Expected code, nested template and include to be uploaded. %v", len(artifactManager.uploads))
+	}
+	var result map[string]interface{}
+	yaml.Unmarshal([]byte(packaged), &result)
+	resources := result["Resources"].(map[string]interface{})
+	property := func(resource string, name string) interface{} {
+		return resources[resource].(map[string]interface{})["Properties"].(map[string]interface{})[name]
+	}
+
+	code := property("Function", "Code").(map[string]interface{})
+	if code["S3Bucket"] != "bucket" || !strings.HasSuffix(code["S3Key"].(string), ".zip") {
+		t.Errorf("Lambda code should point at the zipped upload. %#v", code)
+	}
+	if _, inline := property("InlineFunction", "Code").(map[string]interface{})["ZipFile"]; !inline {
+		t.Error("Inline code should be left untouched.")
+	}
+	if property("RemoteFunction", "CodeUri") != "s3://other/code.zip" {
+		t.Error("Remote artifacts should be left untouched.")
+	}
+	if !strings.HasPrefix(property("Child", "TemplateURL").(string), "https://bucket.") {
+		t.Error("Nested stack template should be referenced by url.")
+	}
+	include := property("Api", "Fn::Transform").(map[string]interface{})["Parameters"].(map[string]interface{})
+	if !strings.HasPrefix(include["Location"].(string), "s3://bucket/") {
+		t.Error("AWS::Include location should be referenced by s3 uri.")
+	}
+	if !strings.Contains(packaged, "!GetAtt") {
+		t.Error("Short form intrinsic functions should be preserved.")
+	}
+}
+
+func TestPackageTemplate_MissingArtifactFails(t *testing.T) {
+	// arrange
+	dir, _ := ioutil.TempDir("", "package")
+	defer os.RemoveAll(dir)
+	templatePath := filepath.Join(dir, "template.yml")
+	writeTestFile(t, templatePath, "Resources:\n  Function:\n    Type: AWS::Serverless::Function\n    Properties:\n      CodeUri: ./missing\n")
+	packager := &templatePackager{
+		ctx:             context.Background(),
+		artifactManager: &mockArtifactManager{},
+		bucket:          "bucket",
+	}
+
+	// act
+	_, err := packager.packageTemplate(templatePath)
+
+	// assert
+	if err == nil {
+		t.Error("Missing artifacts should fail packaging.")
+	}
+}
+
+func TestZipFiles_IsDeterministic(t *testing.T) {
+	// arrange
+	dir, _ := ioutil.TempDir("", "zip")
+	defer os.RemoveAll(dir)
+	writeTestFile(t, filepath.Join(dir, "a.txt"), "a")
+	writeTestFile(t, filepath.Join(dir, "b", "c.txt"), "c")
+
+	// act
+	first, _ := zipDirectory(dir)
+	os.Chtimes(filepath.Join(dir, "a.txt"), time.Now(), time.Now().Add(time.Hour))
+	second, _ := zipDirectory(dir)
+
+	// assert
+	if contentHashKey(first, "") != contentHashKey(second, "") {
+		t.Error("Zipping the same content should produce the same archive.")
+	}
+}
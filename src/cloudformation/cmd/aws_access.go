@@ -4,112 +4,438 @@ import (
 	"errors"
 	"fmt"
 	"github.com/nu7hatch/gouuid"
+	"io"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudformation"
 	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
-	"github.com/aws/aws-sdk-go/service/ec2"
 )
 
 type cfnManagement interface {
-	getStack(stackName *string) (*cloudformation.Stack, error)
-	getStackTemplate(stackName *string) (*string, error)
-	createChangeSet(stackName *string, params []*cloudformation.Parameter, tags []*cloudformation.Tag, templateBody *string, changeSetType string) (*cloudformation.CreateChangeSetOutput, error)
-	executeChangeSet(stackname *string, csName *string) error
-	getTemplateSummary(templateBody *string) (*cloudformation.GetTemplateSummaryOutput, error)
-	getAll(stackChannel chan *cloudformation.Stack, err chan error)
+	getStack(ctx aws.Context, stackName *string) (*cloudformation.Stack, error)
+	getStackTemplate(ctx aws.Context, stackName *string) (*string, error)
+	createChangeSet(ctx aws.Context, stackName *string, params []*cloudformation.Parameter, tags []*cloudformation.Tag, template *cfnTemplate, changeSetType string, options *changeSetOptions) (*cloudformation.CreateChangeSetOutput, error)
+	describeChangeSet(ctx aws.Context, stackName *string, csName *string) (*cloudformation.DescribeChangeSetOutput, error)
+	discardChangeSet(ctx aws.Context, stackName *string, csName *string, changeSetType string) error
+	executeChangeSet(ctx aws.Context, stackname *string, csName *string, events io.Writer) error
+	cancelUpdateStack(ctx aws.Context, stackName *string) error
+	getTemplateSummary(ctx aws.Context, template *cfnTemplate) (*cloudformation.GetTemplateSummaryOutput, error)
+	getAll(ctx aws.Context, stackChannel chan *cloudformation.Stack, err chan error)
 	getRegionCount() int
-	delete(stackName *string) error
+	detectDrift(ctx aws.Context, stackName *string) (*stackDrift, error)
+	inRegion(region string) cfnManagement
+	selectRegions(selection regionSelection)
+	getRegions() []string
+	getDeployRegions() []string
+	selectAccounts(accounts []*account)
+	accountIds() []string
+	inAccount(accountId string) (cfnManagement, error)
+	delete(ctx aws.Context, stackName *string, retainResources []*string) error
+	waitStackDeleted(ctx aws.Context, stackName *string, since time.Time, events io.Writer) error
+	setTerminationProtection(ctx aws.Context, stackName *string, enabled bool) error
+	getStackPolicy(ctx aws.Context, stackName *string) (*string, error)
+	setStackPolicy(ctx aws.Context, stackName *string, policy *string) error
+	listExports(ctx aws.Context) ([]*cloudformation.Export, error)
+	listImports(ctx aws.Context, exportName *string) ([]*string, error)
+	listStackResources(ctx aws.Context, stackName *string) ([]*cloudformation.StackResourceSummary, error)
 }
 
-type cfnManager struct {
-	cfn             cloudformationiface.CloudFormationAPI
-	iamCapabilities []*string
-	cfnRegions      map[string]*cloudformationiface.CloudFormationAPI
-}
-
-func newCfnClient() cfnManagement {
-	var sess = session.Must(session.NewSession(&aws.Config{}))
-
-	ec2client := ec2.New(session.Must(session.NewSession(&aws.Config{
-		Region: aws.String("us-west-2"),
-	})))
-	regions, _ := ec2client.DescribeRegions(&ec2.DescribeRegionsInput{AllRegions: aws.Bool(true)})
-	cfnPerRegion := make(map[string]*cloudformationiface.CloudFormationAPI)
-	for _, region := range regions.Regions {
-		if *region.OptInStatus != "opt-in-not-required" {
-			continue
-		}
+// cfnTemplate is either an inline template body or the url of a template hosted on s3.
+type cfnTemplate struct {
+	body    *string
+	url     *string
+	summary *cloudformation.GetTemplateSummaryOutput // fetched once, see templateSummary
+}
 
-		var cfnRegionClient cloudformationiface.CloudFormationAPI = cloudformation.New(session.Must(session.NewSession(&aws.Config{
-			Region: region.RegionName,
-		})))
-		cfnPerRegion[*region.RegionName] = &cfnRegionClient
-	}
+// stackDrift is the outcome of a drift detection on a stack.
+type stackDrift struct {
+	status    *cloudformation.DescribeStackDriftDetectionStatusOutput
+	resources []*cloudformation.StackResourceDrift // drifted resources only
+}
+
+type cfnManager struct {
+	cfn            cloudformationiface.CloudFormationAPI
+	sess           *session.Session
+	regions        *regionClients            // clients of the session's account
+	accounts       []*account                // from the accounts file, fanned out to instead of the session's account
+	accountRegions map[string]*regionClients // account id to the clients of the account's role
+}
 
+func newCfnClient(sess *session.Session) cfnManagement {
 	var result cfnManagement = &cfnManager{
-		cfn: cloudformation.New(sess),
-		iamCapabilities: []*string{
-			aws.String(cloudformation.CapabilityCapabilityIam),
-			aws.String(cloudformation.CapabilityCapabilityNamedIam),
-			aws.String(cloudformation.CapabilityCapabilityAutoExpand),
-		},
-		cfnRegions: cfnPerRegion,
+		cfn:     cloudformation.New(sess),
+		sess:    sess,
+		regions: newRegionClients(sess),
 	}
 	return result
 }
 
-func (client *cfnManager) delete(stackArn *string) error {
-	region := getRegionFromArn(stackArn)
-	regionClient := *client.cfnRegions[region]
+// delete starts the deletion of a stack. retainResources are logical ids to leave in place, only valid for DELETE_FAILED stacks.
+func (client *cfnManager) delete(ctx aws.Context, stackArn *string, retainResources []*string) error {
 	dsi := &cloudformation.DeleteStackInput{
 		StackName: stackArn,
 	}
-	_, err := regionClient.DeleteStack(dsi)
+	if len(retainResources) > 0 {
+		dsi.RetainResources = retainResources
+	}
+	_, err := client.regionClient(stackArn).DeleteStackWithContext(ctx, dsi)
 	return err
 }
 
-func (client *cfnManager) getRegionCount() int {
-	return len(client.cfnRegions)
+// listStackResources returns the resources of a stack.
+func (client *cfnManager) listStackResources(ctx aws.Context, stackName *string) ([]*cloudformation.StackResourceSummary, error) {
+	resources := make([]*cloudformation.StackResourceSummary, 0)
+	input := &cloudformation.ListStackResourcesInput{
+		StackName: stackName,
+	}
+	for {
+		page, err := client.regionClient(stackName).ListStackResourcesWithContext(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, page.StackResourceSummaries...)
+		if page.NextToken == nil {
+			return resources, nil
+		}
+		input.NextToken = page.NextToken
+	}
 }
 
-func (client *cfnManager) getAll(stackChan chan *cloudformation.Stack, errChan chan error) {
-	for _, rc := range client.cfnRegions {
-		regionClient := *rc
-		go func() {
-			rs, rsErr := regionClient.DescribeStacks(&cloudformation.DescribeStacksInput{})
-			if rsErr != nil {
-				errChan <- rsErr
-				return
+// waitStackDeleted waits until a stack is deleted. since is when the deletion started, to find the failed resource.
+// The stack events are printed to events while waiting, unless it is nil.
+func (client *cfnManager) waitStackDeleted(ctx aws.Context, stackArn *string, since time.Time, events io.Writer) error {
+	regionClient := client.regionClient(stackArn)
+	if events != nil {
+		tailer := newStackEventTailer(ctx, regionClient, events, *stackArn, since)
+		stopTailing := tailer.start()
+		defer stopTailing()
+	}
+
+	waitErr := regionClient.WaitUntilStackDeleteCompleteWithContext(ctx,
+		&cloudformation.DescribeStacksInput{
+			StackName: stackArn,
+		},
+		request.WithWaiterMaxAttempts(0), // No attempt limit. The context carries the deadline.
+		request.WithWaiterDelay(request.ConstantWaiterDelay(15*time.Second)))
+	if waitErr != nil {
+		return explainStackFailure(ctx, regionClient, *stackArn, since, waitErr)
+	}
+	return nil
+}
+
+// setTerminationProtection turns the termination protection of a stack on or off.
+func (client *cfnManager) setTerminationProtection(ctx aws.Context, stackName *string, enabled bool) error {
+	_, err := client.regionClient(stackName).UpdateTerminationProtectionWithContext(ctx, &cloudformation.UpdateTerminationProtectionInput{
+		StackName:                   stackName,
+		EnableTerminationProtection: aws.Bool(enabled),
+	})
+	return err
+}
+
+// getStackPolicy returns the policy of a stack, nil when it has none.
+func (client *cfnManager) getStackPolicy(ctx aws.Context, stackName *string) (*string, error) {
+	result, err := client.regionClient(stackName).GetStackPolicyWithContext(ctx, &cloudformation.GetStackPolicyInput{
+		StackName: stackName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.StackPolicyBody, nil
+}
+
+// setStackPolicy replaces the policy of a stack.
+func (client *cfnManager) setStackPolicy(ctx aws.Context, stackName *string, policy *string) error {
+	_, err := client.regionClient(stackName).SetStackPolicyWithContext(ctx, &cloudformation.SetStackPolicyInput{
+		StackName:       stackName,
+		StackPolicyBody: policy,
+	})
+	return err
+}
+
+// listExports returns the exports of the region of the manager.
+func (client *cfnManager) listExports(ctx aws.Context) ([]*cloudformation.Export, error) {
+	exports := make([]*cloudformation.Export, 0)
+	input := &cloudformation.ListExportsInput{}
+	for {
+		page, err := client.cfn.ListExportsWithContext(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, page.Exports...)
+		if page.NextToken == nil {
+			return exports, nil
+		}
+		input.NextToken = page.NextToken
+	}
+}
+
+// listImports returns the names of the stacks importing an export of the region of the manager.
+func (client *cfnManager) listImports(ctx aws.Context, exportName *string) ([]*string, error) {
+	imports := make([]*string, 0)
+	input := &cloudformation.ListImportsInput{
+		ExportName: exportName,
+	}
+	for {
+		page, err := client.cfn.ListImportsWithContext(ctx, input)
+		if err != nil {
+			// ListImports fails rather than returning an empty list when nothing imports the export.
+			if aerr, ok := err.(awserr.Error); ok && strings.Contains(aerr.Message(), "is not imported by any stack") {
+				return imports, nil
 			}
+			return nil, err
+		}
+		imports = append(imports, page.Imports...)
+		if page.NextToken == nil {
+			return imports, nil
+		}
+		input.NextToken = page.NextToken
+	}
+}
+
+// getRegionCount returns how many regions getAll goes through, over all accounts.
+func (client *cfnManager) getRegionCount() int {
+	count := 0
+	for _, accountId := range client.fanOutAccountIds() {
+		count = count + len(client.clientsOf(accountId).fanOutRegions())
+	}
+	return count
+}
+
+// getRegions returns the regions getAll goes through in the manager's account.
+func (client *cfnManager) getRegions() []string {
+	if client.regions == nil {
+		return nil
+	}
+	return client.regions.fanOutRegions()
+}
+
+// getDeployRegions returns the regions ensure deploys to in the manager's account.
+func (client *cfnManager) getDeployRegions() []string {
+	if client.regions == nil {
+		return nil
+	}
+	return client.regions.deployRegions()
+}
+
+// selectRegions sets the regions getAll goes through. Accounts with regions of their own keep them.
+func (client *cfnManager) selectRegions(selection regionSelection) {
+	client.regions.selectRegions(selection)
+	for _, acct := range client.accounts {
+		accountSelection := selection
+		if len(acct.Regions) > 0 {
+			accountSelection = regionSelection{regions: acct.Regions}
+		}
+		client.accountRegions[acct.AccountId].selectRegions(accountSelection)
+	}
+}
+
+// selectAccounts makes getAll go through the accounts instead of the session's account.
+// Stacks of these accounts are then reached through the account's role. Select the regions afterwards.
+func (client *cfnManager) selectAccounts(accounts []*account) {
+	client.accounts = accounts
+	client.accountRegions = make(map[string]*regionClients)
+	for _, acct := range accounts {
+		client.accountRegions[acct.AccountId] = newRegionClients(assumeRole(client.sess, acct))
+	}
+}
+
+// accountIds returns the accounts of the accounts file, none without one.
+func (client *cfnManager) accountIds() []string {
+	accountIds := make([]string, 0, len(client.accounts))
+	for _, acct := range client.accounts {
+		accountIds = append(accountIds, acct.AccountId)
+	}
+	return accountIds
+}
+
+// inAccount returns a manager that operates on stacks of an account of the accounts file, in the session's region.
+// Unknown accounts mean the current one.
+func (client *cfnManager) inAccount(accountId string) (cfnManagement, error) {
+	regions, exist := client.accountRegions[accountId]
+	if !exist {
+		return client, nil
+	}
+	if regions.sessionRegion == "" {
+		return nil, errors.New(fmt.Sprintf("No region configured to reach account %v. Please set a region (AWS_REGION, profile, ...).", accountId))
+	}
+
+	return &cfnManager{
+		cfn:     regions.client(regions.sessionRegion),
+		regions: regions,
+	}, nil
+}
+
+// fanOutAccountIds returns the accounts getAll goes through. An empty id is the session's account.
+func (client *cfnManager) fanOutAccountIds() []string {
+	if len(client.accounts) == 0 {
+		if client.regions == nil {
+			return nil
+		}
+		return []string{""}
+	}
+	return client.accountIds()
+}
+
+// clientsOf returns the clients of an account of the accounts file, or of the session's account.
+func (client *cfnManager) clientsOf(accountId string) *regionClients {
+	if regions, exist := client.accountRegions[accountId]; exist {
+		return regions
+	}
+	return client.regions
+}
+
+// regionClient returns the client of the account and region in the stack arn. Stack names use the default client,
+// so do malformed arns, for CloudFormation to reject them.
+func (client *cfnManager) regionClient(stackName *string) cloudformationiface.CloudFormationAPI {
+	if !strings.HasPrefix(aws.StringValue(stackName), "arn:") || client.regions == nil {
+		return client.cfn
+	}
+	parsed, arnErr := parseArn(stackName)
+	if arnErr != nil || parsed.Region == "" {
+		return client.cfn
+	}
+	return client.clientsOf(parsed.AccountID).client(parsed.Region)
+}
+
+func (client *cfnManager) detectDrift(ctx aws.Context, stackName *string) (*stackDrift, error) {
+	cfn := client.regionClient(stackName)
+
+	detection, detectErr := cfn.DetectStackDriftWithContext(ctx, &cloudformation.DetectStackDriftInput{
+		StackName: stackName,
+	})
+	if detectErr != nil {
+		return nil, detectErr
+	}
+
+	// Wait for the detection to complete.
+	var status *cloudformation.DescribeStackDriftDetectionStatusOutput
+	for {
+		var statusErr error
+		status, statusErr = cfn.DescribeStackDriftDetectionStatusWithContext(ctx, &cloudformation.DescribeStackDriftDetectionStatusInput{
+			StackDriftDetectionId: detection.StackDriftDetectionId,
+		})
+		if statusErr != nil {
+			return nil, statusErr
+		}
+		if aws.StringValue(status.DetectionStatus) != cloudformation.StackDriftDetectionStatusDetectionInProgress {
+			break
+		}
+		if sleepErr := aws.SleepWithContext(ctx, 5*time.Second); sleepErr != nil {
+			return nil, sleepErr
+		}
+	}
 
-			for _, stack := range rs.Stacks {
-				ss, ssErr := regionClient.DescribeStacks(&cloudformation.DescribeStacksInput{
-					StackName: stack.StackName,
-				})
-				if ssErr != nil {
-					errChan <- ssErr
-					return
+	result := &stackDrift{
+		status:    status,
+		resources: make([]*cloudformation.StackResourceDrift, 0),
+	}
+	input := &cloudformation.DescribeStackResourceDriftsInput{
+		StackName: stackName,
+		StackResourceDriftStatusFilters: aws.StringSlice([]string{
+			cloudformation.StackResourceDriftStatusModified,
+			cloudformation.StackResourceDriftStatusDeleted,
+		}),
+	}
+	for {
+		page, driftsErr := cfn.DescribeStackResourceDriftsWithContext(ctx, input)
+		if driftsErr != nil {
+			return nil, driftsErr
+		}
+		result.resources = append(result.resources, page.StackResourceDrifts...)
+		if page.NextToken == nil {
+			break
+		}
+		input.NextToken = page.NextToken
+	}
+
+	return result, nil
+}
+
+// inRegion returns a manager that operates on stacks in another region. An empty region means the current one.
+func (client *cfnManager) inRegion(region string) cfnManagement {
+	if region == "" {
+		return client
+	}
+
+	regionManager := *client
+	regionManager.cfn = client.regions.client(region)
+	return &regionManager
+}
+
+// newRegionClient returns a client of a region, with the credentials of the session, that retries throttled calls and is rate limited.
+func newRegionClient(sess *session.Session, region string) cloudformationiface.CloudFormationAPI {
+	regionClient := cloudformation.New(sess, &aws.Config{
+		Region:  aws.String(region),
+		Retryer: throttlingRetryer,
+	})
+	throttle(&regionClient.Handlers, newRateLimiter(apiRequestsPerSecond))
+	return regionClient
+}
+
+// regionError is a failure to list the stacks of a region. accountId is empty for the session's account.
+type regionError struct {
+	accountId string
+	region    string
+	err       error
+}
+
+func (e *regionError) Error() string {
+	return fmt.Sprintf("%v: %v", stackLocation{accountId: e.accountId, region: e.region}, e.err)
+}
+
+// getAll streams the stacks of every region of every account to stackChan, followed by a nil once a region is done.
+// A region that fails sends a regionError to errChan before its nil, the other regions carry on.
+func (client *cfnManager) getAll(ctx aws.Context, stackChan chan *cloudformation.Stack, errChan chan error) {
+	for _, accountId := range client.fanOutAccountIds() {
+		regions := client.clientsOf(accountId)
+		for _, region := range regions.fanOutRegions() {
+			client.getAllInRegion(ctx, accountId, region, regions.client(region), stackChan, errChan)
+		}
+	}
+}
+
+// getAllInRegion streams the stacks of a region in the background, as getAll does.
+func (client *cfnManager) getAllInRegion(ctx aws.Context, accountId string, region string, regionClient cloudformationiface.CloudFormationAPI, stackChan chan *cloudformation.Stack, errChan chan error) {
+	go func() {
+		pagesErr := regionClient.DescribeStacksPagesWithContext(ctx, &cloudformation.DescribeStacksInput{}, func(page *cloudformation.DescribeStacksOutput, lastPage bool) bool {
+			for _, stack := range page.Stacks {
+				select {
+				case stackChan <- stack:
+				case <-ctx.Done():
+					return false
 				}
-				stackChan <- ss.Stacks[0]
 			}
+			return true
+		})
 
-			stackChan <- nil
-		}()
-	}
+		if pagesErr != nil {
+			select {
+			case errChan <- &regionError{accountId: accountId, region: region, err: pagesErr}:
+			case <-ctx.Done():
+				return
+			}
+		}
+		select {
+		case stackChan <- nil:
+		case <-ctx.Done():
+		}
+	}()
 }
 
 func (client *cfnManager) createChangeSet(
+	ctx aws.Context,
 	stackName *string,
 	params []*cloudformation.Parameter,
 	tags []*cloudformation.Tag,
-	templateBody *string,
-	changeSetType string) (*cloudformation.CreateChangeSetOutput, error) {
+	template *cfnTemplate,
+	changeSetType string,
+	options *changeSetOptions) (*cloudformation.CreateChangeSetOutput, error) {
 
 	guid, guidErr := uuid.NewV4()
 	if guidErr != nil {
@@ -117,21 +443,25 @@ func (client *cfnManager) createChangeSet(
 	}
 	guidString := "ChangeSet-" + strings.Split(guid.String(), "-")[4]
 
-	usePreviousTemplate := templateBody == nil
+	usePreviousTemplate := template == nil
 
 	csInput := &cloudformation.CreateChangeSetInput{
 		StackName:           stackName,
-		Capabilities:        client.iamCapabilities,
 		Parameters:          params,
 		Tags:                tags,
-		TemplateBody:        templateBody,
 		ChangeSetName:       &guidString,
 		ChangeSetType:       &changeSetType,
 		UsePreviousTemplate: &usePreviousTemplate,
 	}
 
+	if template != nil {
+		csInput.TemplateBody = template.body
+		csInput.TemplateURL = template.url
+	}
+	options.apply(csInput)
+
 	// Create change set.
-	result, changeSetErr := client.cfn.CreateChangeSet(csInput)
+	result, changeSetErr := client.cfn.CreateChangeSetWithContext(ctx, csInput)
 	if changeSetErr != nil {
 		return nil, changeSetErr
 	}
@@ -141,40 +471,118 @@ func (client *cfnManager) createChangeSet(
 		ChangeSetName: result.Id,
 		StackName:     result.StackId,
 	}
-	waitErr := client.cfn.WaitUntilChangeSetCreateComplete(waitInput)
-	if changeSetErr != nil {
+	waitErr := client.cfn.WaitUntilChangeSetCreateCompleteWithContext(ctx, waitInput)
+	if waitErr != nil {
+		// Surface the reason the change set failed, e.g. when it contains no changes.
+		cs, csErr := client.cfn.DescribeChangeSetWithContext(ctx, waitInput)
+		if csErr == nil && cs.StatusReason != nil {
+			return nil, errors.New(fmt.Sprintf("Change set %v failed: %v", *result.Id, *cs.StatusReason))
+		}
 		return nil, waitErr
 	}
 
 	return result, nil
 }
 
-func (client *cfnManager) executeChangeSet(stackname *string, csName *string) error {
+func (client *cfnManager) describeChangeSet(ctx aws.Context, stackName *string, csName *string) (*cloudformation.DescribeChangeSetOutput, error) {
+	input := &cloudformation.DescribeChangeSetInput{
+		ChangeSetName: csName,
+		StackName:     stackName,
+	}
+
+	// Changes are paginated. Fold every page into the first one.
+	var result *cloudformation.DescribeChangeSetOutput
+	for {
+		page, err := client.cfn.DescribeChangeSetWithContext(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		if result == nil {
+			result = page
+		} else {
+			result.Changes = append(result.Changes, page.Changes...)
+		}
+		if page.NextToken == nil {
+			break
+		}
+		input.NextToken = page.NextToken
+	}
+
+	return result, nil
+}
+
+func (client *cfnManager) discardChangeSet(ctx aws.Context, stackName *string, csName *string, changeSetType string) error {
+	_, err := client.cfn.DeleteChangeSetWithContext(ctx, &cloudformation.DeleteChangeSetInput{
+		ChangeSetName: csName,
+		StackName:     stackName,
+	})
+	if err != nil {
+		return err
+	}
+
+	// A create change set leaves an empty stack in REVIEW_IN_PROGRESS behind, so does an import into a new stack.
+	if changeSetType == cloudformation.ChangeSetTypeImport {
+		stacks, describeErr := client.cfn.DescribeStacksWithContext(ctx, &cloudformation.DescribeStacksInput{
+			StackName: stackName,
+		})
+		if describeErr != nil {
+			return describeErr
+		}
+		if len(stacks.Stacks) == 0 || aws.StringValue(stacks.Stacks[0].StackStatus) != cloudformation.StackStatusReviewInProgress {
+			return nil
+		}
+	}
+	if changeSetType == cloudformation.ChangeSetTypeCreate || changeSetType == cloudformation.ChangeSetTypeImport {
+		_, err = client.cfn.DeleteStackWithContext(ctx, &cloudformation.DeleteStackInput{
+			StackName: stackName,
+		})
+	}
+	return err
+}
+
+// executeChangeSet executes a change set and waits for the stack to settle. The stack events are printed to events meanwhile.
+func (client *cfnManager) executeChangeSet(ctx aws.Context, stackname *string, csName *string, events io.Writer) error {
 
 	// Execute changeset.
 	ecsInput := &cloudformation.ExecuteChangeSetInput{
 		ChangeSetName: csName,
 		StackName:     stackname,
 	}
-	_, ecsErr := client.cfn.ExecuteChangeSet(ecsInput)
+	startTime := latestEventTime(ctx, client.cfn, *stackname)
+	_, ecsErr := client.cfn.ExecuteChangeSetWithContext(ctx, ecsInput)
 	if ecsErr != nil {
 		return ecsErr
 	}
 
+	// Print stack events while waiting.
+	tailer := newStackEventTailer(ctx, client.cfn, events, *stackname, startTime)
+	stopTailing := tailer.start()
+
 	// Wait changeset to finishe executing.
 	waitInput := &cloudformation.DescribeStacksInput{
 		StackName: stackname,
 	}
-	return WaitUntilStackCreatedOrUpdated(client.cfn, waitInput)
-	//return client.cfn.WaitUntilStackUpdateComplete(waitInput)
+	waitErr := WaitUntilStackCreatedOrUpdated(ctx, client.cfn, waitInput)
+	stopTailing()
+	if waitErr != nil {
+		return explainStackFailure(ctx, client.cfn, *stackname, startTime, waitErr)
+	}
+	return nil
 }
 
-func WaitUntilStackCreatedOrUpdated(c cloudformationiface.CloudFormationAPI, input *cloudformation.DescribeStacksInput) error {
-	ctx := aws.BackgroundContext()
+func (client *cfnManager) cancelUpdateStack(ctx aws.Context, stackName *string) error {
+	_, err := client.cfn.CancelUpdateStackWithContext(ctx, &cloudformation.CancelUpdateStackInput{
+		StackName: stackName,
+	})
+	return err
+}
+
+// WaitUntilStackCreatedOrUpdated waits until the stack settles or the context is done.
+func WaitUntilStackCreatedOrUpdated(ctx aws.Context, c cloudformationiface.CloudFormationAPI, input *cloudformation.DescribeStacksInput) error {
 	w := request.Waiter{
 		Name:        "WaitUntilStackCreatedOrUpdated",
-		MaxAttempts: 120,
-		Delay:       request.ConstantWaiterDelay(30 * time.Second),
+		MaxAttempts: 0, // No attempt limit. The context carries the deadline.
+		Delay:       request.ConstantWaiterDelay(15 * time.Second),
 		Acceptors: []request.WaiterAcceptor{
 			{
 				State:   request.SuccessWaiterState,
@@ -231,6 +639,21 @@ func WaitUntilStackCreatedOrUpdated(c cloudformationiface.CloudFormationAPI, inp
 				Matcher: request.PathAnyWaiterMatch, Argument: "Stacks[].StackStatus",
 				Expected: "UPDATE_ROLLBACK_COMPLETE",
 			},
+			{
+				State:   request.SuccessWaiterState,
+				Matcher: request.PathAllWaiterMatch, Argument: "Stacks[].StackStatus",
+				Expected: "IMPORT_COMPLETE",
+			},
+			{
+				State:   request.FailureWaiterState,
+				Matcher: request.PathAnyWaiterMatch, Argument: "Stacks[].StackStatus",
+				Expected: "IMPORT_ROLLBACK_FAILED",
+			},
+			{
+				State:   request.FailureWaiterState,
+				Matcher: request.PathAnyWaiterMatch, Argument: "Stacks[].StackStatus",
+				Expected: "IMPORT_ROLLBACK_COMPLETE",
+			},
 			{
 				State:    request.FailureWaiterState,
 				Matcher:  request.ErrorWaiterMatch,
@@ -252,15 +675,16 @@ func WaitUntilStackCreatedOrUpdated(c cloudformationiface.CloudFormationAPI, inp
 	return w.WaitWithContext(ctx)
 }
 
-func (client *cfnManager) getTemplateSummary(templateBody *string) (*cloudformation.GetTemplateSummaryOutput, error) {
+func (client *cfnManager) getTemplateSummary(ctx aws.Context, template *cfnTemplate) (*cloudformation.GetTemplateSummaryOutput, error) {
 	gtsInput := &cloudformation.GetTemplateSummaryInput{
-		TemplateBody: templateBody,
+		TemplateBody: template.body,
+		TemplateURL:  template.url,
 	}
-	return client.cfn.GetTemplateSummary(gtsInput)
+	return client.cfn.GetTemplateSummaryWithContext(ctx, gtsInput)
 }
 
-func (client *cfnManager) getStack(stackName *string) (*cloudformation.Stack, error) {
-	result, err := client.cfn.DescribeStacks(&cloudformation.DescribeStacksInput{
+func (client *cfnManager) getStack(ctx aws.Context, stackName *string) (*cloudformation.Stack, error) {
+	result, err := client.regionClient(stackName).DescribeStacksWithContext(ctx, &cloudformation.DescribeStacksInput{
 		StackName: stackName,
 	})
 
@@ -283,8 +707,8 @@ func (client *cfnManager) getStack(stackName *string) (*cloudformation.Stack, er
 	return result.Stacks[0], nil
 }
 
-func (client *cfnManager) getStackTemplate(stackName *string) (*string, error) {
-	result, err := client.cfn.GetTemplate(&cloudformation.GetTemplateInput{
+func (client *cfnManager) getStackTemplate(ctx aws.Context, stackName *string) (*string, error) {
+	result, err := client.regionClient(stackName).GetTemplateWithContext(ctx, &cloudformation.GetTemplateInput{
 		StackName: stackName,
 	})
 
@@ -295,14 +719,50 @@ func (client *cfnManager) getStackTemplate(stackName *string) (*string, error) {
 	return result.TemplateBody, nil
 }
 
-func getRegionFromArn(arn *string) string {
-	// arn:partition:service:region:account-id:resource
-	segments := strings.Split(*arn, ":")
-	return segments[3]
+func getRegionFromArn(value *string) (string, error) {
+	parsed, err := parseArn(value)
+	if err != nil {
+		return "", err
+	}
+	return parsed.Region, nil
+}
+
+func getAccountIdFromArn(value *string) (string, error) {
+	parsed, err := parseArn(value)
+	if err != nil {
+		return "", err
+	}
+	return parsed.AccountID, nil
 }
 
-func getAccountIdFromArn(arn *string) string {
-	// arn:partition:service:region:account-id:resource
-	segments := strings.Split(*arn, ":")
-	return segments[4]
+// parseArn splits an arn of any partition: arn:partition:service:region:account-id:resource
+func parseArn(value *string) (arn.ARN, error) {
+	parsed, err := arn.Parse(aws.StringValue(value))
+	if err != nil {
+		return arn.ARN{}, errors.New(fmt.Sprintf("Invalid arn %q: %v", aws.StringValue(value), err))
+	}
+	return parsed, nil
+}
+
+// stackLocation is the account and region of a stack. Exports, imports and api rate limits are per account and region.
+type stackLocation struct {
+	accountId string
+	region    string
+}
+
+// locateStack returns the account and region in a stack id. Both are empty when the id is not an arn.
+func locateStack(stackId *string) stackLocation {
+	parsed, err := parseArn(stackId)
+	if err != nil {
+		return stackLocation{}
+	}
+	return stackLocation{accountId: parsed.AccountID, region: parsed.Region}
+}
+
+// String renders the location as account/region, or the region alone when the account is unknown.
+func (l stackLocation) String() string {
+	if l.accountId == "" {
+		return l.region
+	}
+	return l.accountId + "/" + l.region
 }
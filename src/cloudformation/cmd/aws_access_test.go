@@ -1,7 +1,15 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
 )
 
 func TestGetRegionsCount(t *testing.T) {
@@ -17,9 +25,189 @@ func TestGetRegionsCount(t *testing.T) {
 func TestGetRegion(t *testing.T) {
 	arn := "arn:partition:service:region:account-id:resourcetype/resource/qualifier"
 
-	region := getRegionFromArn(&arn)
+	region, err := getRegionFromArn(&arn)
 
-	if region != "region" {
+	if err != nil || region != "region" {
 		t.Error("Region incorrect")
 	}
 }
+
+func TestGetRegion_Partitions(t *testing.T) {
+	cases := map[string]string{
+		"arn:aws:cloudformation:us-east-1:123456789012:stack/a/id":            "us-east-1",
+		"arn:aws-us-gov:cloudformation:us-gov-west-1:123456789012:stack/a/id": "us-gov-west-1",
+		"arn:aws-cn:cloudformation:cn-north-1:123456789012:stack/a/id":        "cn-north-1",
+	}
+
+	for arn, expected := range cases {
+		region, err := getRegionFromArn(aws.String(arn))
+		if err != nil || region != expected {
+			t.Errorf("Arn %v: expected %v, got %v (%v)", arn, expected, region, err)
+		}
+		accountId, accountErr := getAccountIdFromArn(aws.String(arn))
+		if accountErr != nil || accountId != "123456789012" {
+			t.Errorf("Arn %v: unexpected account %v (%v)", arn, accountId, accountErr)
+		}
+	}
+}
+
+func TestGetRegion_MalformedArn(t *testing.T) {
+	for _, arn := range []*string{aws.String("not-an-arn"), aws.String("arn:aws:cloudformation"), nil} {
+		if _, err := getRegionFromArn(arn); err == nil {
+			t.Errorf("Expected an error for %v", aws.StringValue(arn))
+		}
+		if _, err := getAccountIdFromArn(arn); err == nil {
+			t.Errorf("Expected an error for %v", aws.StringValue(arn))
+		}
+	}
+}
+
+func TestCollectStacks_PagesAndReportsRegionErrors(t *testing.T) {
+	// arrange
+	pagedClient := &mockCfnAPI{
+		describeStacksStub: func(input *cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error) {
+			if input.NextToken == nil {
+				return &cloudformation.DescribeStacksOutput{
+					Stacks:    []*cloudformation.Stack{testStack("a"), testStack("b")},
+					NextToken: aws.String("page-2"),
+				}, nil
+			}
+			return &cloudformation.DescribeStacksOutput{
+				Stacks: []*cloudformation.Stack{testStack("c")},
+			}, nil
+		},
+	}
+	failingClient := &mockCfnAPI{
+		describeStacksStub: func(input *cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error) {
+			return nil, errors.New("AccessDenied")
+		},
+	}
+	cm := &CommandManagement{
+		cfnManager: &cfnManager{
+			regions: &regionClients{
+				selection: regionSelection{regions: []string{"us-east-1", "eu-west-1"}},
+				newClient: func(region string) cloudformationiface.CloudFormationAPI {
+					if region == "us-east-1" {
+						return pagedClient
+					}
+					return failingClient
+				},
+				clients: make(map[string]cloudformationiface.CloudFormationAPI),
+			},
+		},
+	}
+
+	// act
+	stacks, err := cm.collectStacks(context.Background())
+
+	// assert
+	if len(stacks) != 3 {
+		t.Errorf("Stacks of every page should be collected. %v", len(stacks))
+	}
+	if err == nil || !strings.Contains(err.Error(), "eu-west-1: AccessDenied") {
+		t.Errorf("The failed region should be reported. %v", err)
+	}
+}
+
+func TestCollectStacks_Accounts(t *testing.T) {
+	// arrange
+	accountClients := func(accountId string) *regionClients {
+		return &regionClients{
+			selection: regionSelection{regions: []string{"us-east-1"}},
+			newClient: func(region string) cloudformationiface.CloudFormationAPI {
+				return &mockCfnAPI{
+					describeStacksStub: func(input *cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error) {
+						if accountId == "222222222222" {
+							return nil, errors.New("AccessDenied")
+						}
+						return &cloudformation.DescribeStacksOutput{
+							Stacks: []*cloudformation.Stack{testStack("a")},
+						}, nil
+					},
+				}
+			},
+			clients: make(map[string]cloudformationiface.CloudFormationAPI),
+		}
+	}
+	cm := &CommandManagement{
+		cfnManager: &cfnManager{
+			regions: accountClients("session"),
+			accounts: []*account{
+				&account{AccountId: "111111111111"},
+				&account{AccountId: "222222222222"},
+			},
+			accountRegions: map[string]*regionClients{
+				"111111111111": accountClients("111111111111"),
+				"222222222222": accountClients("222222222222"),
+			},
+		},
+	}
+
+	// act
+	stacks, err := cm.collectStacks(context.Background())
+
+	// assert
+	if len(stacks) != 1 {
+		t.Errorf("Only the stacks of the accounts should be collected. %v", len(stacks))
+	}
+	if err == nil || !strings.Contains(err.Error(), "222222222222/us-east-1: AccessDenied") {
+		t.Errorf("The failed account should be reported. %v", err)
+	}
+}
+
+func TestRegionClient_RoutesByAccount(t *testing.T) {
+	// arrange
+	sessionApi, accountApi := &mockCfnAPI{}, &mockCfnAPI{}
+	clientsOf := func(api cloudformationiface.CloudFormationAPI) *regionClients {
+		return &regionClients{
+			newClient: func(region string) cloudformationiface.CloudFormationAPI { return api },
+			clients:   make(map[string]cloudformationiface.CloudFormationAPI),
+		}
+	}
+	target := &cfnManager{
+		regions:        clientsOf(sessionApi),
+		accountRegions: map[string]*regionClients{"111111111111": clientsOf(accountApi)},
+	}
+
+	// act
+	inAccount := target.regionClient(aws.String("arn:aws:cloudformation:us-east-1:111111111111:stack/a/id"))
+	inSession := target.regionClient(aws.String("arn:aws:cloudformation:us-east-1:333333333333:stack/a/id"))
+
+	// assert
+	if inAccount != accountApi || inSession != sessionApi {
+		t.Error("Stacks should be reached through the clients of their account.")
+	}
+}
+
+func TestInAccount_NoSessionRegion(t *testing.T) {
+	// arrange
+	target := &cfnManager{
+		accountRegions: map[string]*regionClients{"111111111111": &regionClients{
+			clients: make(map[string]cloudformationiface.CloudFormationAPI),
+		}},
+	}
+
+	// act
+	_, err := target.inAccount("111111111111")
+
+	// assert
+	if err == nil || !strings.Contains(err.Error(), "No region configured") {
+		t.Errorf("An account without a session region should be refused. %v", err)
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	// arrange
+	limiter := newRateLimiter(20)
+	start := time.Now()
+
+	// act
+	for i := 0; i < 3; i++ {
+		limiter.wait(context.Background())
+	}
+
+	// assert
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Calls should be spaced out. %v", elapsed)
+	}
+}
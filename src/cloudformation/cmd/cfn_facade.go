@@ -1,46 +1,221 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/cloudformation"
-	//"strings"
+	"io/ioutil"
+	"strings"
+	"time"
 )
 
-func (cm *CommandManagement) createAndExecute(
-	stackName *string, params []*cloudformation.Parameter, tags []*cloudformation.Tag, templateBody *string, changeSetType string) error {
+// createAndExecute creates a change set, previews it and executes it as the mode says.
+// It reports whether the change set was executed, which it is not in dry and changesetonly modes or when declined.
+func (cm *CommandManagement) createAndExecute(ctx context.Context,
+	stackName *string, params []*cloudformation.Parameter, tags []*cloudformation.Tag, template *cfnTemplate, changeSetType string, options *changeSetOptions) (bool, error) {
 
-	// Create change set
-	if cm.config.mode == dry {
-		// TODO print details.
-		return nil
+	out := cm.output()
+	if policyErr := checkPolicyDuringUpdate(cm.config.mode, options.duringUpdatePolicy()); policyErr != nil {
+		return false, policyErr
+	}
+
+	// Snapshot the stack for the parameter and tag diff. Imports may target a new stack.
+	var oldStack *cloudformation.Stack
+	if changeSetType != cloudformation.ChangeSetTypeCreate {
+		stack, stackErr := cm.cfnManager.getStack(ctx, stackName)
+		if stackErr != nil {
+			return false, stackErr
+		}
+		oldStack = stack
+	}
+
+	// Refuse templates requiring capabilities that were not granted before creating anything.
+	if template != nil {
+		summary, summaryErr := cm.templateSummary(ctx, template)
+		if summaryErr != nil {
+			return false, summaryErr
+		}
+		if capabilitiesErr := checkCapabilities(summary, options.grantedCapabilities()); capabilitiesErr != nil {
+			return false, capabilitiesErr
+		}
 	}
-	createCsOutput, createCsError := cm.cfnManager.createChangeSet(stackName, params, tags, templateBody, changeSetType)
+
+	// Create change set
+	createCsOutput, createCsError := cm.cfnManager.createChangeSet(ctx, stackName, params, tags, template, changeSetType, options)
 	if createCsError != nil {
-		return createCsError
+		return false, createCsError
+	}
+
+	// Preview change set
+	changeSet, describeErr := cm.cfnManager.describeChangeSet(ctx, createCsOutput.StackId, createCsOutput.Id)
+	if describeErr != nil {
+		return false, describeErr
+	}
+	printChangeSet(out, changeSet, oldStack)
+
+	if cm.config.mode == dry {
+		fmt.Fprintln(out, "This is a dry run. Discarding change set...")
+		return false, cm.cfnManager.discardChangeSet(ctx, createCsOutput.StackId, createCsOutput.Id, changeSetType)
 	}
 
 	if cm.config.mode == changesetonly {
-		return nil
+		fmt.Fprintf(out, "Change set created and left for review: %v\n", aws.StringValue(createCsOutput.Id))
+		return false, nil
 	}
 
 	// Execute change set
-	if cm.config.mode == interactive {
-		fmt.Print("Please type \"confirm\" to proceed...")
+	if cm.config.mode == interactive && !cm.confirm(ctx) {
+		return false, nil
+	}
+	// A new stack has no policy to override.
+	restorePolicy := func() error { return nil }
+	if changeSetType == cloudformation.ChangeSetTypeUpdate {
+		restore, overrideErr := cm.overrideStackPolicy(ctx, createCsOutput.StackId, options.duringUpdatePolicy())
+		if overrideErr != nil {
+			return false, overrideErr
+		}
+		restorePolicy = restore
+	}
+	executeErr := cm.cfnManager.executeChangeSet(ctx, createCsOutput.StackId, createCsOutput.Id, out)
+
+	// Roll back an update that is still running when we run out of time.
+	if ctx.Err() == context.DeadlineExceeded && cm.config.cancelOnTimeout && changeSetType == cloudformation.ChangeSetTypeUpdate {
+		fmt.Fprintf(out, "Timed out waiting for %v. Cancelling update...\n", aws.StringValue(stackName))
+		cancelCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if cancelErr := cm.cfnManager.cancelUpdateStack(cancelCtx, createCsOutput.StackId); cancelErr != nil {
+			fmt.Fprintf(out, "Unable to cancel update: %v\n", cancelErr)
+		}
+	}
+
+	restoreErr := restorePolicy()
+	if executeErr != nil {
+		if restoreErr != nil {
+			fmt.Fprintln(out, restoreErr)
+		}
+		return true, executeErr
+	}
+	return true, restoreErr
+}
+
+// confirm asks the user to type "confirm". Returns false when the user declines or the context is done.
+func (cm *CommandManagement) confirm(ctx context.Context) bool {
+	fmt.Print("Please type \"confirm\" to proceed...")
+	answer := make(chan string, 1)
+	go func() {
 		var confirmString string
 		fmt.Scanf("%s", &confirmString)
+		answer <- confirmString
+	}()
+
+	select {
+	case <-ctx.Done():
+		fmt.Println()
+		fmt.Printf("Confirmation aborted: %v\n", ctx.Err())
+		return false
+	case confirmString := <-answer:
 		if confirmString == "confirm" {
 			fmt.Println("Confirmed. Command resuming...")
-		} else {
-			fmt.Println("Confirmation failed. Exiting...")
-			return nil
+			return true
+		}
+		fmt.Println("Confirmation failed. Exiting...")
+		return false
+	}
+}
+
+// maxTemplateBodySize is the largest template CloudFormation accepts inline.
+const maxTemplateBodySize = 51200
+
+// templateSource describes where the template of a stack comes from.
+type templateSource struct {
+	path           string // local template file
+	url            string // template already hosted on s3
+	bucket         string // bucket to stage templates in
+	artifactBucket string // bucket to upload local artifacts referenced by the template to
+}
+
+// loadTemplate resolves the template to deploy from a hosted url, a local file or the template of the existing stack.
+func (cm *CommandManagement) loadTemplate(ctx context.Context, source *templateSource, stackName *string, stackExists bool) (*cfnTemplate, error) {
+	if source.url != "" {
+		return &cfnTemplate{url: aws.String(source.url)}, nil
+	}
+
+	var templateString string
+	if len(source.path) > 0 && source.artifactBucket != "" {
+		// template specified, package local artifacts.
+		packager := &templatePackager{
+			ctx:             ctx,
+			artifactManager: cm.artifactManager,
+			bucket:          source.artifactBucket,
+		}
+		packaged, packageErr := packager.packageTemplate(source.path)
+		if packageErr != nil {
+			return nil, packageErr
 		}
+		templateString = packaged
+	} else if len(source.path) > 0 {
+		// template specified.
+		buffer, templateReadErr := ioutil.ReadFile(source.path)
+		if templateReadErr != nil {
+			return nil, templateReadErr
+		}
+		templateString = string(buffer)
+	} else if stackExists {
+		// template not specified
+		// stack found
+		stackTemplate, stackTemplateErr := cm.cfnManager.getStackTemplate(ctx, stackName)
+		if stackTemplateErr != nil {
+			return nil, stackTemplateErr
+		}
+		templateString = *stackTemplate
+	} else {
+		//template not specified
+		// stack not found
+		return nil, errors.New("No cloudformation template specified and no stack found. Cannot proceed.")
 	}
-	return cm.cfnManager.executeChangeSet(createCsOutput.StackId, createCsOutput.Id)
+
+	return cm.stageTemplate(ctx, templateString, source.bucket)
 }
 
-func (cm *CommandManagement) filterParameters(templateBody *string, values *map[string]string, isUpdate bool) ([]*cloudformation.Parameter, error) {
-	tempSummary, tempSummaryErr := cm.cfnManager.getTemplateSummary(templateBody)
+// stageTemplate uploads the template to the bucket under a content hashed key.
+// Without a bucket the template is passed inline, which only works up to maxTemplateBodySize.
+func (cm *CommandManagement) stageTemplate(ctx context.Context, templateString string, bucket string) (*cfnTemplate, error) {
+	if bucket == "" {
+		if len(templateString) > maxTemplateBodySize {
+			return nil, errors.New(fmt.Sprintf("Template is %v bytes which is over the %v bytes inline limit. Please specify --template-bucket.", len(templateString), maxTemplateBodySize))
+		}
+		return &cfnTemplate{body: &templateString}, nil
+	}
+
+	content := []byte(templateString)
+	url, uploadErr := cm.artifactManager.upload(ctx, bucket, contentHashKey(content, ".template"), content)
+	if uploadErr != nil {
+		return nil, uploadErr
+	}
+	fmt.Printf("Template staged at: %v\n", url)
+	return &cfnTemplate{url: &url}, nil
+}
+
+// templateSummary returns the summary of a template, fetched on first need and kept on the template
+// for filterParameters and createAndExecute to share.
+func (cm *CommandManagement) templateSummary(ctx context.Context, template *cfnTemplate) (*cloudformation.GetTemplateSummaryOutput, error) {
+	if template == nil {
+		return cm.cfnManager.getTemplateSummary(ctx, template)
+	}
+	if template.summary == nil {
+		summary, summaryErr := cm.cfnManager.getTemplateSummary(ctx, template)
+		if summaryErr != nil {
+			return nil, summaryErr
+		}
+		template.summary = summary
+	}
+	return template.summary, nil
+}
+
+func (cm *CommandManagement) filterParameters(ctx context.Context, template *cfnTemplate, values *map[string]string, isUpdate bool) ([]*cloudformation.Parameter, error) {
+	tempSummary, tempSummaryErr := cm.templateSummary(ctx, template)
 	if tempSummaryErr != nil {
 		return nil, tempSummaryErr
 	}
@@ -105,3 +280,37 @@ func (cm *CommandManagement) mergeTags(oldTags []*cloudformation.Tag, newTags *m
 
 	return stackTags
 }
+
+// collectStacks gathers the stacks of every region from the getAll fan-out.
+// Regions that fail are reported and skipped. The stacks of the other regions are returned along with an error naming the failed regions.
+func (cm *CommandManagement) collectStacks(ctx context.Context) ([]*cloudformation.Stack, error) {
+	stackChannel := make(chan *cloudformation.Stack)
+	errChannel := make(chan error)
+
+	go func() {
+		cm.cfnManager.getAll(ctx, stackChannel, errChannel)
+	}()
+
+	stacks := make([]*cloudformation.Stack, 0)
+	failures := make([]string, 0)
+	for i := 0; i < cm.cfnManager.getRegionCount(); {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case err := <-errChannel:
+			fmt.Printf("Unable to list stacks in %v\n", err)
+			failures = append(failures, err.Error())
+		case stack := <-stackChannel:
+			if stack == nil {
+				i = i + 1
+				continue
+			}
+			stacks = append(stacks, stack)
+		}
+	}
+
+	if len(failures) > 0 {
+		return stacks, errors.New(fmt.Sprintf("Unable to list stacks in %v region(s): %v", len(failures), strings.Join(failures, "; ")))
+	}
+	return stacks, nil
+}
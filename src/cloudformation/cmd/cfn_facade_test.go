@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+)
+
+func TestCreateAndExecute_DryDiscardsChangeSet(t *testing.T) {
+	// arrange
+	discarded := false
+	executed := false
+	mockCfnManager := &mockCfnManager{
+		createChangeSetStub: func(stackName *string, params []*cloudformation.Parameter, tags []*cloudformation.Tag, template *cfnTemplate, changeSetType string) (*cloudformation.CreateChangeSetOutput, error) {
+			return &cloudformation.CreateChangeSetOutput{Id: aws.String("cs"), StackId: aws.String("stack")}, nil
+		},
+		discardChangeSetStub: func(stackName *string, csName *string, changeSetType string) error {
+			discarded = *csName == "cs" && changeSetType == cloudformation.ChangeSetTypeCreate
+			return nil
+		},
+		executeChangeSetStub: func(stackname *string, csName *string) error {
+			executed = true
+			return nil
+		},
+	}
+	cm := &CommandManagement{
+		cfnManager: mockCfnManager,
+		config:     &config{mode: dry},
+	}
+
+	// act
+	reported, err := cm.createAndExecute(context.Background(), aws.String("stack"), nil, nil, &cfnTemplate{body: aws.String("")}, cloudformation.ChangeSetTypeCreate, nil)
+
+	// assert
+	if err != nil {
+		t.Error("createAndExecute should not fail in dry mode.")
+	}
+	if reported {
+		t.Error("createAndExecute should report the change set was not executed.")
+	}
+	if !discarded {
+		t.Error("Change set should be discarded in dry mode.")
+	}
+	if executed {
+		t.Error("Change set should not be executed in dry mode.")
+	}
+}
+
+func TestCreateAndExecute_MissingCapabilities(t *testing.T) {
+	// arrange
+	created := false
+	mockCfnManager := &mockCfnManager{
+		getTemplateSummaryStub: func(template *cfnTemplate) (*cloudformation.GetTemplateSummaryOutput, error) {
+			return &cloudformation.GetTemplateSummaryOutput{
+				Capabilities:       aws.StringSlice([]string{cloudformation.CapabilityCapabilityNamedIam}),
+				CapabilitiesReason: aws.String("The following resource(s) require capabilities: [AWS::IAM::Role]"),
+			}, nil
+		},
+		createChangeSetStub: func(stackName *string, params []*cloudformation.Parameter, tags []*cloudformation.Tag, template *cfnTemplate, changeSetType string) (*cloudformation.CreateChangeSetOutput, error) {
+			created = true
+			return &cloudformation.CreateChangeSetOutput{Id: aws.String("cs"), StackId: aws.String("stack")}, nil
+		},
+	}
+	cm := &CommandManagement{
+		cfnManager: mockCfnManager,
+		config:     &config{mode: dry},
+	}
+
+	// act
+	_, err := cm.createAndExecute(context.Background(), aws.String("stack"), nil, nil, &cfnTemplate{body: aws.String("")}, cloudformation.ChangeSetTypeCreate, &changeSetOptions{})
+
+	// assert
+	if err == nil || !strings.Contains(err.Error(), "CAPABILITY_NAMED_IAM") {
+		t.Errorf("createAndExecute should fail naming the missing capability. %v", err)
+	}
+	if created {
+		t.Error("No change set should be created without the capabilities.")
+	}
+}
+
+func TestCreateAndExecute_ReusesTemplateSummary(t *testing.T) {
+	// arrange
+	summaries := 0
+	cm := &CommandManagement{
+		cfnManager: &mockCfnManager{
+			getTemplateSummaryStub: func(template *cfnTemplate) (*cloudformation.GetTemplateSummaryOutput, error) {
+				summaries++
+				return &cloudformation.GetTemplateSummaryOutput{
+					Parameters: []*cloudformation.ParameterDeclaration{
+						&cloudformation.ParameterDeclaration{ParameterKey: aws.String("Env")},
+					},
+				}, nil
+			},
+		},
+		config: &config{mode: noninteractive},
+	}
+	template := &cfnTemplate{body: aws.String("")}
+	values := map[string]string{"Env": "prod"}
+
+	// act
+	params, paramsErr := cm.filterParameters(context.Background(), template, &values, false)
+	_, err := cm.createAndExecute(context.Background(), aws.String("stack"), params, nil, template, cloudformation.ChangeSetTypeCreate, &changeSetOptions{})
+
+	// assert
+	if paramsErr != nil || err != nil {
+		t.Fatal(paramsErr, err)
+	}
+	if summaries != 1 {
+		t.Errorf("The template summary should be fetched once. %v", summaries)
+	}
+}
+
+func TestCreateAndExecute_ChangeSetOnlyKeepsChangeSet(t *testing.T) {
+	// arrange
+	discarded := false
+	executed := false
+	mockCfnManager := &mockCfnManager{
+		discardChangeSetStub: func(stackName *string, csName *string, changeSetType string) error {
+			discarded = true
+			return nil
+		},
+		executeChangeSetStub: func(stackname *string, csName *string) error {
+			executed = true
+			return nil
+		},
+	}
+	cm := &CommandManagement{
+		cfnManager: mockCfnManager,
+		config:     &config{mode: changesetonly},
+	}
+
+	// act
+	_, err := cm.createAndExecute(context.Background(), aws.String("stack"), nil, nil, &cfnTemplate{body: aws.String("")}, cloudformation.ChangeSetTypeUpdate, nil)
+
+	// assert
+	if err != nil {
+		t.Error("createAndExecute should not fail in changesetonly mode.")
+	}
+	if discarded || executed {
+		t.Error("Change set should be left untouched in changesetonly mode.")
+	}
+}
+
+func TestCreateAndExecute_CancelsUpdateOnTimeout(t *testing.T) {
+	// arrange
+	cancelled := false
+	mockCfnManager := &mockCfnManager{
+		executeChangeSetStub: func(stackname *string, csName *string) error {
+			return context.DeadlineExceeded
+		},
+		cancelUpdateStackStub: func(stackName *string) error {
+			cancelled = true
+			return nil
+		},
+	}
+	cm := &CommandManagement{
+		cfnManager: mockCfnManager,
+		config:     &config{mode: noninteractive, cancelOnTimeout: true},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	<-ctx.Done()
+
+	// act
+	_, err := cm.createAndExecute(ctx, aws.String("stack"), nil, nil, &cfnTemplate{body: aws.String("")}, cloudformation.ChangeSetTypeUpdate, nil)
+
+	// assert
+	if err == nil {
+		t.Error("createAndExecute should report the time out.")
+	}
+	if !cancelled {
+		t.Error("Update should be cancelled when the time out is reached.")
+	}
+}
+
+func TestLoadTemplate_StagesInBucket(t *testing.T) {
+	// arrange
+	templateFile, _ := ioutil.TempFile("", "template")
+	defer os.Remove(templateFile.Name())
+	templateFile.WriteString("Resources: {}")
+	templateFile.Close()
+	artifactManager := &mockArtifactManager{}
+	cm := &CommandManagement{
+		cfnManager:      &mockCfnManager{},
+		artifactManager: artifactManager,
+	}
+
+	// act
+	template, err := cm.loadTemplate(context.Background(), &templateSource{path: templateFile.Name(), bucket: "bucket"}, aws.String("stack"), false)
+
+	// assert
+	if err != nil {
+		t.Fatalf("loadTemplate should not fail. %v", err)
+	}
+	if template.body != nil || template.url == nil {
+		t.Error("Staged template should be referenced by url.")
+	}
+	if len(artifactManager.uploads) != 1 {
+		t.Error("Template should be uploaded once.")
+	}
+	key := "bucket/" + contentHashKey([]byte("Resources: {}"), ".template")
+	if _, exist := artifactManager.uploads[key]; !exist {
+		t.Errorf("Template should be uploaded under a content hashed key. %#v", artifactManager.uploads)
+	}
+}
+
+func TestLoadTemplate_RejectsLargeInlineTemplate(t *testing.T) {
+	// arrange
+	largeTemplate := strings.Repeat(" ", maxTemplateBodySize+1)
+	cm := &CommandManagement{
+		cfnManager: &mockCfnManager{
+			getStackTemplateStub: func(stackName *string) (*string, error) {
+				return &largeTemplate, nil
+			},
+		},
+	}
+
+	// act
+	_, err := cm.loadTemplate(context.Background(), &templateSource{}, aws.String("stack"), true)
+
+	// assert
+	if err == nil {
+		t.Error("Templates over the inline limit should require a bucket.")
+	}
+}
+
+func TestLoadTemplate_UsesHostedUrl(t *testing.T) {
+	// arrange
+	cm := &CommandManagement{cfnManager: &mockCfnManager{}}
+
+	// act
+	template, err := cm.loadTemplate(context.Background(), &templateSource{url: "https://bucket.s3.amazonaws.com/key"}, aws.String("stack"), false)
+
+	// assert
+	if err != nil || *template.url != "https://bucket.s3.amazonaws.com/key" {
+		t.Error("Hosted template url should be used as is.")
+	}
+}
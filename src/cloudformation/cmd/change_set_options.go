@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// Most minutes CloudFormation monitors the rollback triggers for after a deployment.
+const maxRollbackMonitoringMinutes = 180
+
+// changeSetOptions are the stack settings a change set carries besides the template, parameters and tags.
+// Settings left nil keep the values of the stack on updates.
+type changeSetOptions struct {
+	roleArn               *string
+	notificationArns      []*string
+	rollbackConfiguration *cloudformation.RollbackConfiguration
+	resourceTypes         []*string
+	capabilities          []*string // nil grants none
+	policyDuringUpdate    *string   // not part of the change set: swapped in while it executes
+	resourcesToImport     []*cloudformation.ResourceToImport
+}
+
+// addChangeSetFlags registers the flags of the change set options on a command.
+func addChangeSetFlags(cmd *cobra.Command) {
+	cmd.Flags().String("service-role-arn", "", "Role CloudFormation deploys the stack with, instead of the caller's credentials")
+	cmd.Flags().StringSlice("notification-arns", nil, "SNS topics to send the stack events to")
+	cmd.Flags().StringSlice("rollback-alarm", nil, "CloudWatch alarms that roll the deployment back when they go off, as arn or arn=AWS::CloudWatch::CompositeAlarm")
+	cmd.Flags().Int("rollback-monitoring-minutes", 0, "Minutes to keep monitoring the rollback alarms for once the resources are deployed")
+	cmd.Flags().StringSlice("resource-types", nil, "Resource types the template may use, e.g. AWS::S3::*")
+	addCapabilitiesFlag(cmd)
+	cmd.Flags().String("policy-during-update", "", "Json or yaml stack policy overriding the policy of the stack while it updates")
+}
+
+// addCapabilitiesFlag registers the flag granting capabilities to change sets.
+func addCapabilitiesFlag(cmd *cobra.Command) {
+	cmd.Flags().StringSlice("capabilities", nil, fmt.Sprintf("Capabilities to grant the change set. Valid values are: %v.", strings.Join(cloudformation.Capability_Values(), ", ")))
+}
+
+// readCapabilities reads the capabilities granted. Unknown ones are returned as errstrings.
+func readCapabilities(localViper *viper.Viper) ([]*string, []string) {
+	var errstrings []string
+	capabilities := localViper.GetStringSlice("capabilities")
+	if len(capabilities) == 0 {
+		return nil, nil
+	}
+	for _, capability := range capabilities {
+		if !contains(cloudformation.Capability_Values(), capability) {
+			errstrings = append(errstrings, fmt.Sprintf("Invalid capability %#v. Valid values are: %v.", capability, strings.Join(cloudformation.Capability_Values(), ", ")))
+		}
+	}
+	return aws.StringSlice(capabilities), errstrings
+}
+
+// readChangeSetOptions builds the change set options from their flags. Invalid values are returned as errstrings.
+func readChangeSetOptions(localViper *viper.Viper) (*changeSetOptions, []string) {
+	var errstrings []string
+	options := &changeSetOptions{}
+
+	if roleArn := localViper.GetString("service-role-arn"); roleArn != "" {
+		if parsed, err := parseArn(aws.String(roleArn)); err != nil || parsed.Service != "iam" {
+			errstrings = append(errstrings, fmt.Sprintf("Invalid service-role-arn %#v.", roleArn))
+		}
+		options.roleArn = aws.String(roleArn)
+	}
+
+	if localViper.IsSet("notification-arns") {
+		options.notificationArns = make([]*string, 0)
+		for _, topicArn := range localViper.GetStringSlice("notification-arns") {
+			if parsed, err := parseArn(aws.String(topicArn)); err != nil || parsed.Service != "sns" {
+				errstrings = append(errstrings, fmt.Sprintf("Invalid notification arn %#v.", topicArn))
+			}
+			options.notificationArns = append(options.notificationArns, aws.String(topicArn))
+		}
+	}
+
+	alarms := localViper.GetStringSlice("rollback-alarm")
+	monitoring := localViper.GetInt("rollback-monitoring-minutes")
+	if len(alarms) > 0 || monitoring > 0 {
+		options.rollbackConfiguration = &cloudformation.RollbackConfiguration{
+			MonitoringTimeInMinutes: aws.Int64(int64(monitoring)),
+			RollbackTriggers:        make([]*cloudformation.RollbackTrigger, 0, len(alarms)),
+		}
+	}
+	for _, alarm := range alarms {
+		arnType := strings.SplitN(alarm, "=", 2)
+		triggerType := "AWS::CloudWatch::Alarm"
+		if len(arnType) == 2 {
+			triggerType = arnType[1]
+		}
+		if parsed, err := parseArn(aws.String(arnType[0])); err != nil || parsed.Service != "cloudwatch" {
+			errstrings = append(errstrings, fmt.Sprintf("Invalid rollback-alarm %#v.", alarm))
+		}
+		options.rollbackConfiguration.RollbackTriggers = append(options.rollbackConfiguration.RollbackTriggers, &cloudformation.RollbackTrigger{
+			Arn:  aws.String(arnType[0]),
+			Type: aws.String(triggerType),
+		})
+	}
+	if monitoring < 0 || monitoring > maxRollbackMonitoringMinutes {
+		errstrings = append(errstrings, fmt.Sprintf("Invalid rollback-monitoring-minutes %v. It has to be between 0 and %v.", monitoring, maxRollbackMonitoringMinutes))
+	}
+
+	if resourceTypes := localViper.GetStringSlice("resource-types"); len(resourceTypes) > 0 {
+		options.resourceTypes = aws.StringSlice(resourceTypes)
+	}
+
+	if policyPath := localViper.GetString("policy-during-update"); policyPath != "" {
+		policy, policyErr := readStackPolicy(policyPath)
+		if policyErr != nil {
+			errstrings = append(errstrings, policyErr.Error())
+		}
+		options.policyDuringUpdate = policy
+	}
+
+	capabilities, capabilityErrs := readCapabilities(localViper)
+	options.capabilities = capabilities
+	errstrings = append(errstrings, capabilityErrs...)
+
+	return options, errstrings
+}
+
+func (o *changeSetOptions) empty() bool {
+	return o.roleArn == nil && o.notificationArns == nil && o.rollbackConfiguration == nil && o.resourceTypes == nil
+}
+
+// apply sets the options on a change set input.
+func (o *changeSetOptions) apply(input *cloudformation.CreateChangeSetInput) {
+	if o == nil {
+		return
+	}
+	input.RoleARN = o.roleArn
+	input.NotificationARNs = o.notificationArns
+	input.RollbackConfiguration = o.rollbackConfiguration
+	input.ResourceTypes = o.resourceTypes
+	input.Capabilities = o.capabilities
+	input.ResourcesToImport = o.resourcesToImport
+}
+
+// grantedCapabilities returns the capabilities granted, none without options.
+func (o *changeSetOptions) grantedCapabilities() []*string {
+	if o == nil {
+		return nil
+	}
+	return o.capabilities
+}
+
+// duringUpdatePolicy returns the policy to update the stack with, nil without options.
+func (o *changeSetOptions) duringUpdatePolicy() *string {
+	if o == nil {
+		return nil
+	}
+	return o.policyDuringUpdate
+}
+
+// checkCapabilities fails when a template requires capabilities that were not granted, with the reason CloudFormation gives.
+// CAPABILITY_NAMED_IAM covers the templates requiring CAPABILITY_IAM.
+func checkCapabilities(summary *cloudformation.GetTemplateSummaryOutput, granted []*string) error {
+	grantedValues := aws.StringValueSlice(granted)
+	missing := make([]string, 0)
+	for _, required := range aws.StringValueSlice(summary.Capabilities) {
+		if contains(grantedValues, required) {
+			continue
+		}
+		if required == cloudformation.CapabilityCapabilityIam && contains(grantedValues, cloudformation.CapabilityCapabilityNamedIam) {
+			continue
+		}
+		missing = append(missing, required)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return errors.New(fmt.Sprintf("The template requires capabilities that were not granted: %v\n%v\nGrant them with --capabilities %v or capabilities in the config file.",
+		strings.Join(missing, ", "), aws.StringValue(summary.CapabilitiesReason), strings.Join(missing, ",")))
+}
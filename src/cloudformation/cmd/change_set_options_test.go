@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/spf13/viper"
+)
+
+func TestReadChangeSetOptions(t *testing.T) {
+	// arrange
+	localViper := viper.New()
+	localViper.Set("service-role-arn", "arn:aws:iam::111111111111:role/cfn-deploy")
+	localViper.Set("notification-arns", []string{"arn:aws:sns:us-east-1:111111111111:stack-events"})
+	localViper.Set("rollback-alarm", []string{
+		"arn:aws:cloudwatch:us-east-1:111111111111:alarm:errors",
+		"arn:aws:cloudwatch:us-east-1:111111111111:alarm:health=AWS::CloudWatch::CompositeAlarm",
+	})
+	localViper.Set("rollback-monitoring-minutes", 10)
+	localViper.Set("resource-types", []string{"AWS::S3::*"})
+
+	// act
+	options, errstrings := readChangeSetOptions(localViper)
+	input := &cloudformation.CreateChangeSetInput{}
+	options.apply(input)
+
+	// assert
+	if len(errstrings) > 0 {
+		t.Fatal(errstrings)
+	}
+	if aws.StringValue(input.RoleARN) != "arn:aws:iam::111111111111:role/cfn-deploy" || len(input.NotificationARNs) != 1 || len(input.ResourceTypes) != 1 {
+		t.Errorf("Options not applied. %v", input)
+	}
+	triggers := input.RollbackConfiguration.RollbackTriggers
+	if aws.Int64Value(input.RollbackConfiguration.MonitoringTimeInMinutes) != 10 || len(triggers) != 2 {
+		t.Fatalf("Rollback configuration incorrect. %v", input.RollbackConfiguration)
+	}
+	if aws.StringValue(triggers[0].Type) != "AWS::CloudWatch::Alarm" || aws.StringValue(triggers[1].Type) != "AWS::CloudWatch::CompositeAlarm" {
+		t.Errorf("Trigger types incorrect. %v", triggers)
+	}
+}
+
+func TestReadChangeSetOptions_Invalid(t *testing.T) {
+	cases := map[string]interface{}{
+		"service-role-arn":            "arn:aws:sns:us-east-1:111111111111:topic",
+		"notification-arns":           []string{"topic"},
+		"rollback-alarm":              []string{"arn:aws:sns:us-east-1:111111111111:topic"},
+		"rollback-monitoring-minutes": 181,
+	}
+
+	for key, value := range cases {
+		// arrange
+		localViper := viper.New()
+		localViper.Set(key, value)
+
+		// act
+		_, errstrings := readChangeSetOptions(localViper)
+
+		// assert
+		if len(errstrings) != 1 {
+			t.Errorf("Expected %v to be refused, got %v", key, errstrings)
+		}
+	}
+}
+
+func TestReadChangeSetOptions_NoneGiven(t *testing.T) {
+	// act
+	options, errstrings := readChangeSetOptions(viper.New())
+
+	// assert
+	if len(errstrings) > 0 || !options.empty() {
+		t.Errorf("Nothing given should keep the settings of the stack. %#v", options)
+	}
+}
+
+func TestReadCapabilities(t *testing.T) {
+	// arrange
+	localViper := viper.New()
+	localViper.Set("capabilities", []string{"CAPABILITY_NAMED_IAM", "CAPABILITY_ROOT"})
+
+	// act
+	capabilities, errstrings := readCapabilities(localViper)
+
+	// assert
+	if len(capabilities) != 2 {
+		t.Errorf("Capabilities not read. %v", aws.StringValueSlice(capabilities))
+	}
+	if len(errstrings) != 1 || !strings.Contains(errstrings[0], "CAPABILITY_ROOT") {
+		t.Errorf("Unknown capability should be reported. %v", errstrings)
+	}
+}
+
+func TestCheckCapabilities(t *testing.T) {
+	// arrange
+	summary := &cloudformation.GetTemplateSummaryOutput{
+		Capabilities:       aws.StringSlice([]string{cloudformation.CapabilityCapabilityIam, cloudformation.CapabilityCapabilityAutoExpand}),
+		CapabilitiesReason: aws.String("The following resource(s) require capabilities: [AWS::IAM::Role]"),
+	}
+
+	// act
+	noneErr := checkCapabilities(summary, nil)
+	namedErr := checkCapabilities(summary, aws.StringSlice([]string{cloudformation.CapabilityCapabilityNamedIam}))
+	allErr := checkCapabilities(summary, aws.StringSlice([]string{cloudformation.CapabilityCapabilityNamedIam, cloudformation.CapabilityCapabilityAutoExpand}))
+
+	// assert
+	if noneErr == nil || !strings.Contains(noneErr.Error(), "CAPABILITY_IAM, CAPABILITY_AUTO_EXPAND") || !strings.Contains(noneErr.Error(), "AWS::IAM::Role") {
+		t.Errorf("Missing capabilities should be named with the reason. %v", noneErr)
+	}
+	if namedErr == nil || strings.Contains(namedErr.Error(), "CAPABILITY_IAM") {
+		t.Errorf("CAPABILITY_NAMED_IAM should cover CAPABILITY_IAM only. %v", namedErr)
+	}
+	if allErr != nil {
+		t.Errorf("Granted capabilities should pass. %v", allErr)
+	}
+}
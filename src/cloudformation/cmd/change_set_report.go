@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+)
+
+// printChangeSet renders the resource changes, parameter changes and tag changes of a change set.
+// oldStack is the stack as it was before the change set was created. nil when the stack is new.
+func printChangeSet(out io.Writer, cs *cloudformation.DescribeChangeSetOutput, oldStack *cloudformation.Stack) {
+	fmt.Fprintf(out, "Change set: %v (%v)\n", aws.StringValue(cs.ChangeSetName), aws.StringValue(cs.Status))
+	if reason := aws.StringValue(cs.StatusReason); reason != "" {
+		fmt.Fprintf(out, "Status reason: %v\n", reason)
+	}
+
+	var oldParams []*cloudformation.Parameter
+	var oldTags []*cloudformation.Tag
+	if oldStack != nil {
+		oldParams = oldStack.Parameters
+		oldTags = oldStack.Tags
+	}
+
+	fmt.Fprintln(out)
+	printResourceChanges(out, cs.Changes)
+	fmt.Fprintln(out)
+	printParameterChanges(out, oldParams, cs.Parameters)
+	fmt.Fprintln(out)
+	printTagChanges(out, oldTags, cs.Tags)
+}
+
+func printResourceChanges(out io.Writer, changes []*cloudformation.Change) {
+	fmt.Fprintln(out, "Resource changes:")
+	if len(changes) == 0 {
+		fmt.Fprintln(out, "  (none)")
+		return
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "  ACTION\tLOGICAL ID\tPHYSICAL ID\tRESOURCE TYPE\tREPLACEMENT\tSCOPE\tCAUSED BY")
+	for _, change := range changes {
+		rc := change.ResourceChange
+		if rc == nil {
+			continue
+		}
+		fmt.Fprintf(tw, "  %v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+			aws.StringValue(rc.Action),
+			aws.StringValue(rc.LogicalResourceId),
+			orDash(aws.StringValue(rc.PhysicalResourceId)),
+			aws.StringValue(rc.ResourceType),
+			orDash(aws.StringValue(rc.Replacement)),
+			orDash(strings.Join(aws.StringValueSlice(rc.Scope), ",")),
+			orDash(describeCauses(rc.Details)))
+	}
+	tw.Flush()
+}
+
+// describeCauses summarises what triggered a resource change, e.g. "Properties.BucketName <- ParameterReference:TestPath".
+func describeCauses(details []*cloudformation.ResourceChangeDetail) string {
+	causes := make([]string, 0, len(details))
+	seen := make(map[string]bool)
+	for _, detail := range details {
+		cause := aws.StringValue(detail.ChangeSource)
+		if entity := aws.StringValue(detail.CausingEntity); entity != "" {
+			cause = cause + ":" + entity
+		}
+		if target := detail.Target; target != nil {
+			targetName := aws.StringValue(target.Attribute)
+			if name := aws.StringValue(target.Name); name != "" {
+				targetName = targetName + "." + name
+			}
+			cause = targetName + " <- " + cause
+		}
+		if seen[cause] {
+			continue
+		}
+		seen[cause] = true
+		causes = append(causes, cause)
+	}
+	return strings.Join(causes, ", ")
+}
+
+func printParameterChanges(out io.Writer, oldParams []*cloudformation.Parameter, newParams []*cloudformation.Parameter) {
+	fmt.Fprintln(out, "Parameters:")
+
+	oldValues := make(map[string]string)
+	for _, param := range oldParams {
+		oldValues[aws.StringValue(param.ParameterKey)] = aws.StringValue(param.ParameterValue)
+	}
+	newValues := make(map[string]string)
+	for _, param := range newParams {
+		newValues[aws.StringValue(param.ParameterKey)] = aws.StringValue(param.ParameterValue)
+	}
+
+	keys := sortedKeys(oldValues, newValues)
+	if len(keys) == 0 {
+		fmt.Fprintln(out, "  (none)")
+		return
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "    KEY\tOLD\tNEW")
+	for _, key := range keys {
+		oldValue, oldExist := oldValues[key]
+		newValue, newExist := newValues[key]
+		fmt.Fprintf(tw, "  %v %v\t%v\t%v\n", diffMarker(oldExist, newExist, oldValue != newValue), key, orDash(oldValue), orDash(newValue))
+	}
+	tw.Flush()
+}
+
+func printTagChanges(out io.Writer, oldTags []*cloudformation.Tag, newTags []*cloudformation.Tag) {
+	fmt.Fprintln(out, "Tags:")
+
+	oldValues := make(map[string]string)
+	for _, tag := range oldTags {
+		oldValues[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	newValues := make(map[string]string)
+	for _, tag := range newTags {
+		newValues[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+
+	keys := sortedKeys(oldValues, newValues)
+	if len(keys) == 0 {
+		fmt.Fprintln(out, "  (none)")
+		return
+	}
+
+	for _, key := range keys {
+		oldValue, oldExist := oldValues[key]
+		newValue, newExist := newValues[key]
+		switch diffMarker(oldExist, newExist, oldValue != newValue) {
+		case "+":
+			fmt.Fprintf(out, "  + %v: %v\n", key, newValue)
+		case "-":
+			fmt.Fprintf(out, "  - %v: %v\n", key, oldValue)
+		case "~":
+			fmt.Fprintf(out, "  ~ %v: %v -> %v\n", key, oldValue, newValue)
+		default:
+			fmt.Fprintf(out, "    %v: %v\n", key, newValue)
+		}
+	}
+}
+
+// diffMarker returns "+" for added, "-" for removed, "~" for modified and " " for unchanged values.
+func diffMarker(oldExist bool, newExist bool, changed bool) string {
+	switch {
+	case !oldExist && newExist:
+		return "+"
+	case oldExist && !newExist:
+		return "-"
+	case changed:
+		return "~"
+	default:
+		return " "
+	}
+}
+
+func sortedKeys(maps ...map[string]string) []string {
+	keySet := make(map[string]bool)
+	for _, m := range maps {
+		for key := range m {
+			keySet[key] = true
+		}
+	}
+	keys := make([]string, 0, len(keySet))
+	for key := range keySet {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
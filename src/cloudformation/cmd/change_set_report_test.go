@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+)
+
+func TestPrintChangeSet_ResourceChanges(t *testing.T) {
+	// arrange
+	cs := &cloudformation.DescribeChangeSetOutput{
+		ChangeSetName: aws.String("ChangeSet-abc"),
+		Status:        aws.String(cloudformation.ChangeSetStatusCreateComplete),
+		Changes: []*cloudformation.Change{
+			&cloudformation.Change{
+				ResourceChange: &cloudformation.ResourceChange{
+					Action:             aws.String(cloudformation.ChangeActionModify),
+					LogicalResourceId:  aws.String("Bucket"),
+					PhysicalResourceId: aws.String("my-bucket"),
+					ResourceType:       aws.String("AWS::S3::Bucket"),
+					Replacement:        aws.String(cloudformation.ReplacementTrue),
+					Scope:              aws.StringSlice([]string{cloudformation.ResourceAttributeProperties}),
+					Details: []*cloudformation.ResourceChangeDetail{
+						&cloudformation.ResourceChangeDetail{
+							ChangeSource:  aws.String(cloudformation.ChangeSourceParameterReference),
+							CausingEntity: aws.String("TestPath"),
+							Target: &cloudformation.ResourceTargetDefinition{
+								Attribute: aws.String(cloudformation.ResourceAttributeProperties),
+								Name:      aws.String("BucketName"),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	out := &bytes.Buffer{}
+
+	// act
+	printChangeSet(out, cs, nil)
+
+	// assert
+	result := out.String()
+	for _, expected := range []string{"ChangeSet-abc", "Modify", "Bucket", "my-bucket", "AWS::S3::Bucket", "True", "Properties.BucketName <- ParameterReference:TestPath"} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Change set preview is missing %#v.\n%v", expected, result)
+		}
+	}
+}
+
+func TestPrintChangeSet_ParameterAndTagDiff(t *testing.T) {
+	// arrange
+	oldStack := &cloudformation.Stack{
+		Parameters: []*cloudformation.Parameter{
+			&cloudformation.Parameter{ParameterKey: aws.String("a"), ParameterValue: aws.String("va")},
+			&cloudformation.Parameter{ParameterKey: aws.String("b"), ParameterValue: aws.String("vb")},
+		},
+		Tags: []*cloudformation.Tag{
+			&cloudformation.Tag{Key: aws.String("x"), Value: aws.String("vx")},
+			&cloudformation.Tag{Key: aws.String("y"), Value: aws.String("vy")},
+		},
+	}
+	cs := &cloudformation.DescribeChangeSetOutput{
+		Parameters: []*cloudformation.Parameter{
+			&cloudformation.Parameter{ParameterKey: aws.String("a"), ParameterValue: aws.String("vaa")},
+			&cloudformation.Parameter{ParameterKey: aws.String("c"), ParameterValue: aws.String("vc")},
+		},
+		Tags: []*cloudformation.Tag{
+			&cloudformation.Tag{Key: aws.String("x"), Value: aws.String("vxx")},
+			&cloudformation.Tag{Key: aws.String("z"), Value: aws.String("vz")},
+		},
+	}
+	out := &bytes.Buffer{}
+
+	// act
+	printChangeSet(out, cs, oldStack)
+
+	// assert
+	result := out.String()
+	for _, expected := range []string{"~ a", "- b", "+ c", "~ x: vx -> vxx", "- y: vy", "+ z: vz"} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Change set preview is missing %#v.\n%v", expected, result)
+		}
+	}
+}
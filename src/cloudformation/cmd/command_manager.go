@@ -1,6 +1,11 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -10,15 +15,20 @@ type CommandManager interface {
 }
 
 type CommandManagement struct {
-	root       *cobra.Command
-	config     *config
-	cfnManager cfnManagement
-	viper      *viper.Viper
+	root            *cobra.Command
+	config          *config
+	cfnManager      cfnManagement
+	artifactManager artifactManagement
+	resourcePurger  resourcePurging
+	viper           *viper.Viper
+	ctx             context.Context
+	out             io.Writer // change set previews and stack events, os.Stdout when nil
 }
 
 type config struct {
-	mode    mode
-	timeout int
+	mode            mode
+	timeout         int
+	cancelOnTimeout bool
 }
 
 type mode int
@@ -52,3 +62,71 @@ func ParseMode(value string) mode {
 func (cm *CommandManagement) Execute() error {
 	return cm.root.Execute()
 }
+
+// context returns the context commands should pass to every aws call.
+// It is cancelled on SIGINT / SIGTERM and when the --wait time out is reached.
+func (cm *CommandManagement) context() context.Context {
+	if cm.ctx == nil {
+		return context.Background()
+	}
+	return cm.ctx
+}
+
+// output returns where change set previews, stack events and execution progress are printed.
+func (cm *CommandManagement) output() io.Writer {
+	if cm.out == nil {
+		return os.Stdout
+	}
+	return cm.out
+}
+
+// prefixWriter starts every line written through it with a prefix, e.g. the account and region of a stack
+// when several are worked on in a row.
+type prefixWriter struct {
+	out     io.Writer
+	prefix  string
+	midLine bool
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	for _, line := range bytes.SplitAfter(p, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		if !w.midLine {
+			if _, err := io.WriteString(w.out, w.prefix); err != nil {
+				return 0, err
+			}
+		}
+		if _, err := w.out.Write(line); err != nil {
+			return 0, err
+		}
+		w.midLine = line[len(line)-1] != '\n'
+	}
+	return len(p), nil
+}
+
+// Exit codes of the cli.
+const (
+	exitOk             = 0
+	exitError          = 1
+	exitRolledBack     = 2
+	exitRollbackFailed = 3
+	exitDrifted        = 4
+)
+
+// exitCoder is implemented by errors that map to a specific exit code.
+type exitCoder interface {
+	ExitCode() int
+}
+
+// ExitCode returns the process exit code for an error returned by Execute.
+func ExitCode(err error) int {
+	if err == nil {
+		return exitOk
+	}
+	if coder, ok := err.(exitCoder); ok {
+		return coder.ExitCode()
+	}
+	return exitError
+}
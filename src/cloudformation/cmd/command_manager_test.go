@@ -1,6 +1,9 @@
 package cmd
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"testing"
 )
 
@@ -23,3 +26,31 @@ func TestModeParse(t *testing.T) {
 		t.Error("Parsing failed.")
 	}
 }
+
+func TestExitCode(t *testing.T) {
+	if ExitCode(nil) != exitOk {
+		t.Error("No error should exit with 0.")
+	}
+	if ExitCode(errors.New("failed")) != exitError {
+		t.Error("Plain errors should exit with 1.")
+	}
+	if ExitCode(&stackFailureError{stackStatus: "UPDATE_ROLLBACK_FAILED"}) != exitRollbackFailed {
+		t.Error("Errors should be able to choose their exit code.")
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	// arrange
+	var out bytes.Buffer
+	writer := &prefixWriter{out: &out, prefix: "111111111111/us-east-1: "}
+
+	// act
+	fmt.Fprint(writer, "Change set: cs\n\nResource")
+	fmt.Fprintln(writer, " changes:")
+
+	// assert
+	expected := "111111111111/us-east-1: Change set: cs\n111111111111/us-east-1: \n111111111111/us-east-1: Resource changes:\n"
+	if out.String() != expected {
+		t.Errorf("Every line should be prefixed once. %q", out.String())
+	}
+}
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/spf13/cobra"
+	"sort"
+	"strings"
+)
+
+// noEchoMask is what CloudFormation returns instead of the value of a NoEcho parameter.
+const noEchoMask = "****"
+
+type copyCmd struct {
+	source         string
+	target         string
+	targetRegion   string
+	params         map[string]string
+	tags           map[string]string
+	templateBucket string
+	capabilities   []*string // nil grants the capabilities of the source stack
+	cm             *CommandManagement
+	cmd            *cobra.Command
+}
+
+func (uc *copyCmd) runE(cmd *cobra.Command, args []string) error {
+
+	cfnManager := uc.cm.cfnManager
+	ctx := uc.cm.context()
+
+	// Source stack
+	sourceStack, sourceErr := cfnManager.getStack(ctx, &uc.source)
+	if sourceErr != nil {
+		return sourceErr
+	}
+	if sourceStack == nil {
+		return errors.New(fmt.Sprintf("Source stack %v not found.", uc.source))
+	}
+	sourceTemplate, sourceTemplateErr := cfnManager.getStackTemplate(ctx, &uc.source)
+	if sourceTemplateErr != nil {
+		return sourceTemplateErr
+	}
+
+	// Target stack
+	targetManager := cfnManager.inRegion(uc.targetRegion)
+	targetStack, targetErr := targetManager.getStack(ctx, &uc.target)
+	if targetErr != nil {
+		return targetErr
+	}
+	if targetStack != nil {
+		return errors.New(fmt.Sprintf("Target stack %v already exists.", uc.target))
+	}
+
+	// Parameter values of the source stack, overridden by the ones specified.
+	values, valuesErr := copyParameterValues(sourceStack.Parameters, uc.params)
+	if valuesErr != nil {
+		return valuesErr
+	}
+
+	// Deploy through a copy of the command manager bound to the target region.
+	targetCm := *uc.cm
+	targetCm.cfnManager = targetManager
+
+	template, templateErr := targetCm.stageTemplate(ctx, *sourceTemplate, uc.templateBucket)
+	if templateErr != nil {
+		return templateErr
+	}
+	stackParams, spErr := targetCm.filterParameters(ctx, template, &values, false)
+	if spErr != nil {
+		return spErr
+	}
+	stackTags := targetCm.mergeTags(sourceStack.Tags, &uc.tags)
+
+	// The copy is granted what the source stack was deployed with, unless capabilities are specified.
+	options := &changeSetOptions{capabilities: sourceStack.Capabilities}
+	if uc.capabilities != nil {
+		options.capabilities = uc.capabilities
+	}
+
+	_, executeErr := targetCm.createAndExecute(ctx, &uc.target, stackParams, stackTags, template, cloudformation.ChangeSetTypeCreate, options)
+	return executeErr
+}
+
+// copyParameterValues merges the parameters of the source stack with the overrides.
+// NoEcho parameters cannot be read back, so they have to be overridden.
+func copyParameterValues(sourceParams []*cloudformation.Parameter, overrides map[string]string) (map[string]string, error) {
+	values := make(map[string]string)
+	missing := make([]string, 0)
+	for _, param := range sourceParams {
+		key := aws.StringValue(param.ParameterKey)
+		if override, exist := overrides[key]; exist {
+			values[key] = override
+		} else if aws.StringValue(param.ParameterValue) == noEchoMask {
+			missing = append(missing, key)
+		} else {
+			values[key] = aws.StringValue(param.ParameterValue)
+		}
+	}
+	for key, value := range overrides {
+		values[key] = value
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, errors.New(fmt.Sprintf("NoEcho parameters cannot be copied. Please specify them with --param: %v", strings.Join(missing, ", ")))
+	}
+	return values, nil
+}
+
+func (uc *copyCmd) preRunE(cmd *cobra.Command, args []string) error {
+
+	localViper := uc.cm.viper
+	uc.source = localViper.GetString("source")
+	uc.target = localViper.GetString("target")
+	uc.targetRegion = localViper.GetString("target-region")
+	uc.params = localViper.GetStringMapString("param")
+	uc.tags = localViper.GetStringMapString("tag")
+	uc.templateBucket = localViper.GetString("template-bucket")
+
+	// parameter validations
+	capabilities, errstrings := readCapabilities(localViper)
+	uc.capabilities = capabilities
+	if uc.source == "" {
+		errstrings = append(errstrings, "Please specify source stack to copy.")
+	}
+	if uc.target == "" {
+		errstrings = append(errstrings, "Please specify target stack name.")
+	}
+
+	if len(errstrings) > 0 {
+		return errors.New(strings.Join(errstrings, "\n"))
+	}
+
+	return nil
+}
+
+var copyCmdLong = `Create a new stack from the template, parameters and tags of an existing stack, optionally in another region.`
+
+func (cm *CommandManagement) initCopyCmd() {
+
+	// init command structure
+	cmd := &cobra.Command{
+		Use:   "copy",
+		Short: "copy",
+		Long:  copyCmdLong,
+	}
+	ucmd := &copyCmd{
+		cm:  cm,
+		cmd: cmd,
+	}
+
+	// local params
+	cmd.Flags().StringP("source", "s", "", "Stack name or arn to copy")
+	cmd.Flags().StringP("target", "t", "", "Name of the stack to create")
+	cmd.Flags().String("target-region", "", "Region to create the stack in. Defaults to the current region")
+	cmd.Flags().StringToStringP("param", "p", nil, "Parameters to override")
+	cmd.Flags().StringToStringP("tag", "g", nil, "Tags to override")
+	cmd.Flags().String("template-bucket", "", "S3 bucket to stage the template in. Required for templates over 51200 bytes")
+	addCapabilitiesFlag(cmd)
+
+	// wire methods.
+	cmd.PreRunE = ucmd.preRunE
+	cmd.RunE = ucmd.runE
+
+	// register
+	cm.root.AddCommand(cmd)
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/spf13/viper"
+)
+
+func TestCopyCmdPreRunE_ValidatesParams(t *testing.T) {
+	ucmd := &copyCmd{
+		cm: &CommandManagement{
+			viper: viper.New(),
+		},
+	}
+
+	err := ucmd.preRunE(nil, nil)
+
+	if err == nil {
+		t.Error("Command copy parameters validation failed.")
+	}
+}
+
+func TestCopyCmdRunE_CreatesStackInTargetRegion(t *testing.T) {
+	// arrange
+	var resultParams []*cloudformation.Parameter
+	var resultTags []*cloudformation.Tag
+	var resultType string
+	targetRegion := ""
+	targetManager := &mockCfnManager{
+		getStackStub: func(stackName *string) (*cloudformation.Stack, error) {
+			return nil, nil
+		},
+		getTemplateSummaryStub: func(template *cfnTemplate) (*cloudformation.GetTemplateSummaryOutput, error) {
+			return &cloudformation.GetTemplateSummaryOutput{
+				Parameters: []*cloudformation.ParameterDeclaration{
+					&cloudformation.ParameterDeclaration{ParameterKey: aws.String("a")},
+					&cloudformation.ParameterDeclaration{ParameterKey: aws.String("b")},
+				},
+			}, nil
+		},
+		createChangeSetStub: func(stackName *string, params []*cloudformation.Parameter, tags []*cloudformation.Tag, template *cfnTemplate, changeSetType string) (*cloudformation.CreateChangeSetOutput, error) {
+			resultParams = params
+			resultTags = tags
+			resultType = changeSetType
+			return &cloudformation.CreateChangeSetOutput{}, nil
+		},
+	}
+	sourceManager := &mockCfnManager{
+		getStackStub: func(stackName *string) (*cloudformation.Stack, error) {
+			return &cloudformation.Stack{
+				Parameters: []*cloudformation.Parameter{
+					&cloudformation.Parameter{ParameterKey: aws.String("a"), ParameterValue: aws.String("va")},
+					&cloudformation.Parameter{ParameterKey: aws.String("b"), ParameterValue: aws.String("vb")},
+				},
+				Tags: []*cloudformation.Tag{
+					&cloudformation.Tag{Key: aws.String("x"), Value: aws.String("vx")},
+				},
+			}, nil
+		},
+		inRegionStub: func(region string) cfnManagement {
+			targetRegion = region
+			return targetManager
+		},
+	}
+	ucmd := &copyCmd{
+		cm: &CommandManagement{
+			cfnManager: sourceManager,
+			config:     &config{mode: noninteractive},
+		},
+		source:       "source",
+		target:       "target",
+		targetRegion: "eu-west-1",
+		params:       map[string]string{"b": "vbb"},
+		tags:         map[string]string{"y": "vy"},
+	}
+
+	// act
+	err := ucmd.runE(nil, nil)
+
+	// assert
+	if err != nil {
+		t.Fatalf("Command copy should not fail. %v", err)
+	}
+	if targetRegion != "eu-west-1" {
+		t.Error("Stack should be created in the target region.")
+	}
+	if resultType != cloudformation.ChangeSetTypeCreate {
+		t.Error("Copy should create a new stack.")
+	}
+	if len(resultParams) != 2 || *resultParams[0].ParameterValue != "va" || *resultParams[1].ParameterValue != "vbb" {
+		t.Errorf("Parameters should be copied and overridden. %#v", resultParams)
+	}
+	if len(resultTags) != 2 {
+		t.Error("Tags should be copied and merged.")
+	}
+}
+
+func TestCopyParameterValues_NoEchoRequiresOverride(t *testing.T) {
+	// arrange
+	sourceParams := []*cloudformation.Parameter{
+		&cloudformation.Parameter{ParameterKey: aws.String("secret"), ParameterValue: aws.String(noEchoMask)},
+	}
+
+	// act
+	_, err := copyParameterValues(sourceParams, map[string]string{})
+	values, overrideErr := copyParameterValues(sourceParams, map[string]string{"secret": "s"})
+
+	// assert
+	if err == nil {
+		t.Error("NoEcho parameters should require an override.")
+	}
+	if overrideErr != nil || values["secret"] != "s" {
+		t.Error("Overridden NoEcho parameters should be copied.")
+	}
+}
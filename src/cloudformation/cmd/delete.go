@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+type deleteCmd struct {
+	target         string
+	forceUnprotect bool
+	retainFailed   bool
+	retainedReport string
+	purge          bool
+	cm             *CommandManagement
+	cmd            *cobra.Command
+}
+
+func (uc *deleteCmd) runE(cmd *cobra.Command, args []string) error {
+
+	cfnManager := uc.cm.cfnManager
+	ctx := uc.cm.context()
+
+	stack, stackErr := cfnManager.getStack(ctx, &uc.target)
+	if stackErr != nil {
+		return stackErr
+	}
+	if stack == nil {
+		return errors.New(fmt.Sprintf("Stack %v not found.", uc.target))
+	}
+	protected := aws.BoolValue(stack.EnableTerminationProtection)
+	if protected && !uc.forceUnprotect {
+		return errors.New(fmt.Sprintf("Stack %v has termination protection enabled. Use --force-unprotect to delete it anyway.", aws.StringValue(stack.StackName)))
+	}
+
+	region, arnErr := getRegionFromArn(stack.StackId)
+	if arnErr != nil {
+		return arnErr
+	}
+
+	// Show what is about to go.
+	resources, resourcesErr := cfnManager.listStackResources(ctx, stack.StackId)
+	if resourcesErr != nil {
+		return resourcesErr
+	}
+	fmt.Printf("Deleting stack: %v (%v - %v)\n", aws.StringValue(stack.StackName), region, aws.StringValue(stack.StackStatus))
+	printStackResources(os.Stdout, resources)
+	if protected {
+		fmt.Println("Termination protection will be disabled.")
+	}
+
+	if uc.cm.config.mode == dry {
+		fmt.Println("This is a dry run. The stack was not deleted.")
+		return nil
+	}
+	if uc.cm.config.mode == interactive && !uc.cm.confirm(ctx) {
+		return nil
+	}
+
+	if protected {
+		if unprotectErr := cfnManager.setTerminationProtection(ctx, stack.StackId, false); unprotectErr != nil {
+			return unprotectErr
+		}
+	}
+
+	deleter := &stackDeleter{
+		cfnManager:   cfnManager,
+		retainFailed: uc.retainFailed,
+		events:       os.Stdout,
+	}
+	if uc.purge {
+		deleter.purger = uc.cm.resourcePurger
+	}
+	if delErr := deleter.deleteStack(ctx, stack.StackId); delErr != nil {
+		if protected {
+			restoreProtection(cfnManager, stack)
+		}
+		return delErr
+	}
+	fmt.Printf("Deleted stack: %v\n", aws.StringValue(stack.StackName))
+
+	// Deleted stacks can still be looked up by id, with the outcome of each resource.
+	removed, removedErr := cfnManager.listStackResources(ctx, stack.StackId)
+	if removedErr != nil {
+		fmt.Printf("Unable to list the removed resources: %v\n", removedErr)
+	} else {
+		printStackResources(os.Stdout, removed)
+	}
+
+	return deleter.writeReport(uc.retainedReport)
+}
+
+// restoreProtection enables termination protection again on a stack that was unprotected to be deleted, but wasn't.
+// It runs even when the command ran out of time or was cancelled.
+func restoreProtection(cfnManager cfnManagement, stack *cloudformation.Stack) {
+	restoreCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	fmt.Printf("Deletion did not complete. Enabling termination protection on %v again...\n", aws.StringValue(stack.StackName))
+	if restoreErr := cfnManager.setTerminationProtection(restoreCtx, stack.StackId, true); restoreErr != nil {
+		fmt.Printf("Unable to enable termination protection: %v\nTermination protection of %v is still disabled.\n", restoreErr, aws.StringValue(stack.StackName))
+	}
+}
+
+// printStackResources renders the status, logical id, type and physical id of stack resources.
+func printStackResources(out io.Writer, resources []*cloudformation.StackResourceSummary) {
+	fmt.Fprintln(out, "Resources:")
+	if len(resources) == 0 {
+		fmt.Fprintln(out, "  (none)")
+		return
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "  STATUS\tLOGICAL ID\tRESOURCE TYPE\tPHYSICAL ID")
+	for _, resource := range resources {
+		fmt.Fprintf(tw, "  %v\t%v\t%v\t%v\n",
+			aws.StringValue(resource.ResourceStatus),
+			aws.StringValue(resource.LogicalResourceId),
+			aws.StringValue(resource.ResourceType),
+			orDash(aws.StringValue(resource.PhysicalResourceId)))
+	}
+	tw.Flush()
+}
+
+func (uc *deleteCmd) preRunE(cmd *cobra.Command, args []string) error {
+
+	localViper := uc.cm.viper
+	uc.target = localViper.GetString("target")
+	uc.forceUnprotect = localViper.GetBool("force-unprotect")
+	uc.retainFailed = localViper.GetBool("retain-failed")
+	uc.retainedReport = localViper.GetString("retained-report")
+	uc.purge = localViper.GetBool("purge")
+
+	// parameter validations
+	var errstrings []string
+	if uc.target == "" {
+		errstrings = append(errstrings, "Please specify target stack to delete.")
+	}
+	if uc.cm.config.mode == changesetonly {
+		errstrings = append(errstrings, "Mode changesetonly is not allowed for delete cmd.")
+	}
+
+	if len(errstrings) > 0 {
+		return errors.New(strings.Join(errstrings, "\n"))
+	}
+
+	return nil
+}
+
+var deleteCmdLong = `Delete a stack, printing its events until it is gone, and report the resources removed.
+Stacks with termination protection are refused unless --force-unprotect is given.`
+
+func (cm *CommandManagement) initDeleteCmd() {
+
+	// init command structure
+	cmd := &cobra.Command{
+		Use:   "delete",
+		Short: "delete",
+		Long:  deleteCmdLong,
+	}
+	ucmd := &deleteCmd{
+		cm:  cm,
+		cmd: cmd,
+	}
+
+	// local params
+	cmd.Flags().StringP("target", "t", "", "Stack name or arn to delete")
+	cmd.Flags().Bool("force-unprotect", false, "Disable termination protection to delete a protected stack")
+	addDeletionFlags(cmd)
+
+	// wire methods.
+	cmd.PreRunE = ucmd.preRunE
+	cmd.RunE = ucmd.runE
+
+	// register
+	cm.root.AddCommand(cmd)
+}
@@ -1,75 +1,186 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/cloudformation"
 	"github.com/spf13/cobra"
+	"os"
+	"strings"
+	"sync"
 )
 
 type deleteAllCmd struct {
-	cm  *CommandManagement
-	cmd *cobra.Command
+	filter         *stackFilter
+	concurrency    int
+	retainFailed   bool
+	retainedReport string
+	purge          bool
+	cm             *CommandManagement
+	cmd            *cobra.Command
 }
 
 func (uc *deleteAllCmd) runE(cmd *cobra.Command, args []string) error {
 
-	cfnManager := uc.cm.cfnManager
+	ctx := uc.cm.context()
 
-	stackChannel := make(chan *cloudformation.Stack)
-	defer close(stackChannel)
-	errChannel := make(chan error)
-	defer close(errChannel)
-
-	go func() {
-		cfnManager.getAll(stackChannel, errChannel)
-	}()
+	// Regions that cannot be listed are left alone and reported at the end.
+	allStacks, listErr := uc.cm.collectStacks(ctx)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
 
+	// Select the stacks before asking anything, so the prompt covers exactly what will be deleted.
+	filter := uc.filter
+	if filter == nil {
+		filter = &stackFilter{}
+	}
+	summary := &deletionSummary{}
 	stacks := make([]*cloudformation.Stack, 0)
-	for i := 0; i < cfnManager.getRegionCount(); {
-		select {
-		case err := <-errChannel:
-			return err
-		case stack := <-stackChannel:
-			if stack == nil {
-				i = i + 1
-				continue
-			}
+	for _, stack := range allStacks {
+		if aws.BoolValue(stack.EnableTerminationProtection) {
+			fmt.Printf("Skipping protected stack: %v (%v)\n", aws.StringValue(stack.StackName), locateStack(stack.StackId))
+			summary.add(stack, resultProtected, "termination protection")
+			continue
+		}
+		if reason := filter.match(stack); reason != "" {
+			fmt.Printf("Skipping filtered out stack: %v (%v - %v)\n", aws.StringValue(stack.StackName), locateStack(stack.StackId), reason)
+			summary.add(stack, resultFiltered, reason)
+			continue
+		}
+		stacks = append(stacks, stack)
+	}
 
-			if *stack.EnableTerminationProtection {
-				continue
-			}
+	plan, planErr := uc.cm.planDeletion(ctx, allStacks, stacks)
+	if planErr != nil {
+		return planErr
+	}
+	for _, stack := range stacks {
+		if reason, skipped := plan.skipped[aws.StringValue(stack.StackId)]; skipped {
+			fmt.Printf("Skipping stack: %v (%v - %v)\n", *stack.StackName, locateStack(stack.StackId), reason)
+			summary.add(stack, resultSkipped, reason)
+		}
+	}
+	for i, wave := range plan.waves {
+		fmt.Printf("Wave %v:\n", i+1)
+		for _, stack := range wave {
+			fmt.Printf("  Deleting stack: %v (%v - %v)\n", *stack.StackName, locateStack(stack.StackId), *stack.StackStatus)
+		}
+	}
+	fmt.Printf("%v of %v stack(s) selected for deletion in %v wave(s).\n", len(stacks)-len(plan.skipped), len(allStacks), len(plan.waves))
 
-			stacks = append(stacks, stack)
-
-			fmt.Printf("Deleting stack: %v (%v - %v)\n", *stack.StackName, getRegionFromArn(stack.StackId), *stack.StackStatus)
-			if uc.cm.config.mode == interactive {
-				fmt.Print("Please type \"confirm\" to proceed...")
-				var confirmString string
-				fmt.Scanf("%s", &confirmString)
-				if confirmString == "confirm" {
-					fmt.Println("Confirmed. Command resuming...")
-				} else {
-					fmt.Println("Confirmation failed. Exiting...")
-					return nil
-				}
-			}
+	if uc.cm.config.mode == dry {
+		fmt.Println("This is a dry run. No stacks were deleted.")
+		return listErr
+	}
+	if len(plan.waves) == 0 {
+		return listErr
+	}
+	if uc.cm.config.mode == interactive && !uc.cm.confirm(ctx) {
+		return nil
+	}
 
-			if uc.cm.config.mode == dry {
+	// Delete a wave, wait for it, then move on to the stacks it imported from.
+	deleter := &stackDeleter{
+		cfnManager:   uc.cm.cfnManager,
+		retainFailed: uc.retainFailed,
+	}
+	if uc.purge {
+		deleter.purger = uc.cm.resourcePurger
+	}
+	for i, wave := range plan.waves {
+		fmt.Printf("Deleting wave %v of %v.\n", i+1, len(plan.waves))
+		deleting := make([]*cloudformation.Stack, 0, len(wave))
+		for _, stack := range wave {
+			if reason, skipped := plan.skipped[aws.StringValue(stack.StackId)]; skipped {
+				fmt.Printf("Skipping stack: %v (%v - %v)\n", *stack.StackName, locateStack(stack.StackId), reason)
+				summary.add(stack, resultSkipped, reason)
 				continue
 			}
+			deleting = append(deleting, stack)
+		}
 
-			if delErr := cfnManager.delete(stack.StackId); delErr != nil {
-				return delErr
+		for _, outcome := range uc.deleteWave(ctx, deleter, deleting) {
+			summary.outcomes = append(summary.outcomes, outcome)
+			if outcome.result == resultFailed {
+				plan.block(aws.StringValue(outcome.stack.StackId), "imported by failed stack "+*outcome.stack.StackName)
 			}
 		}
+		if ctx.Err() != nil {
+			break
+		}
 	}
 
-	if uc.cm.config.mode == dry {
-		fmt.Println("This is a dry run. No stacks were deleted.")
+	summary.print(os.Stdout)
+	if reportErr := deleter.writeReport(uc.retainedReport); reportErr != nil {
+		return reportErr
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if failed := summary.count(resultFailed); failed > 0 {
+		return errors.New(fmt.Sprintf("Failed to delete %v stack(s).", failed))
 	}
 
-	return nil
+	return listErr
+}
+
+// deleteWave deletes stacks and waits for them, with at most concurrency stacks in progress per account and region.
+func (uc *deleteAllCmd) deleteWave(ctx context.Context, deleter *stackDeleter, stacks []*cloudformation.Stack) []*deletionOutcome {
+	locations := make([]stackLocation, 0)
+	stacksByLocation := make(map[stackLocation][]*cloudformation.Stack)
+	for _, stack := range stacks {
+		location := locateStack(stack.StackId)
+		if _, exist := stacksByLocation[location]; !exist {
+			locations = append(locations, location)
+		}
+		stacksByLocation[location] = append(stacksByLocation[location], stack)
+	}
+
+	outcomes := make(chan *deletionOutcome)
+	var wg sync.WaitGroup
+	for _, location := range locations {
+		queue := make(chan *cloudformation.Stack, len(stacksByLocation[location]))
+		for _, stack := range stacksByLocation[location] {
+			queue <- stack
+		}
+		close(queue)
+
+		workers := uc.concurrency
+		if workers < 1 {
+			workers = 1
+		}
+		for i := 0; i < workers && i < len(stacksByLocation[location]); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for stack := range queue {
+					if delErr := deleter.deleteStack(ctx, stack.StackId); delErr != nil {
+						outcomes <- &deletionOutcome{stack: stack, result: resultFailed, reason: delErr.Error()}
+						continue
+					}
+					outcomes <- &deletionOutcome{stack: stack, result: resultDeleted}
+				}
+			}()
+		}
+	}
+	go func() {
+		wg.Wait()
+		close(outcomes)
+	}()
+
+	result := make([]*deletionOutcome, 0, len(stacks))
+	for outcome := range outcomes {
+		if outcome.result == resultFailed {
+			fmt.Printf("Failed to delete stack %v (%v): %v\n", *outcome.stack.StackName, locateStack(outcome.stack.StackId), outcome.reason)
+		} else {
+			fmt.Printf("Deleted stack: %v (%v)\n", *outcome.stack.StackName, locateStack(outcome.stack.StackId))
+		}
+		result = append(result, outcome)
+	}
+	return result
 }
 
 func (uc *deleteAllCmd) preRunE(cmd *cobra.Command, args []string) error {
@@ -78,10 +189,21 @@ func (uc *deleteAllCmd) preRunE(cmd *cobra.Command, args []string) error {
 		return errors.New("Mode changesetonly is not allowed for delete-all cmd.")
 	}
 
+	filter, errstrings := readStackFilter(uc.cm.viper)
+	uc.concurrency = uc.cm.viper.GetInt("concurrency")
+	uc.retainFailed = uc.cm.viper.GetBool("retain-failed")
+	uc.retainedReport = uc.cm.viper.GetString("retained-report")
+	uc.purge = uc.cm.viper.GetBool("purge")
+	if len(errstrings) > 0 {
+		return errors.New(strings.Join(errstrings, "\n"))
+	}
+	uc.filter = filter
+
 	return nil
 }
 
-var deleteAllCmdLong = `Delete all stacks in all regions.`
+var deleteAllCmdLong = `Delete all stacks in the selected regions and accounts, except those with termination protection.
+The selector flags narrow down the stacks to delete. Every selector given must match.`
 
 func (cm *CommandManagement) initDeleteAllCmd() {
 
@@ -96,6 +218,11 @@ func (cm *CommandManagement) initDeleteAllCmd() {
 		cmd: cmd,
 	}
 
+	// local params
+	addStackFilterFlags(cmd)
+	cmd.Flags().Int("concurrency", 5, "Number of stacks to delete at the same time per account and region")
+	addDeletionFlags(cmd)
+
 	// wire methods.
 	cmd.PreRunE = cmdContainer.preRunE
 	cmd.RunE = cmdContainer.runE
@@ -1,9 +1,12 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/spf13/viper"
 	"testing"
 )
 
@@ -12,6 +15,7 @@ func TestDeleteAllCmdPreRunE_ValidatesHasUpdateParams(t *testing.T) {
 		cm: &CommandManagement{
 			cfnManager: &mockCfnManager{},
 			config:     &config{mode: noninteractive},
+			viper:      viper.New(),
 		},
 	}
 
@@ -68,3 +72,168 @@ func TestDeleteAllCmdPreRunE_Success(t *testing.T) {
 		t.Error("Incorrect number of stacks")
 	}
 }
+
+func TestDeleteAllCmdRunE_Filtered(t *testing.T) {
+	// arrange
+	deletedStacks := make([]string, 0)
+	mockCfnManager := &mockCfnManager{
+		getAllStub: func(stackChan chan *cloudformation.Stack, errChan chan error) {
+			stackChan <- &cloudformation.Stack{
+				StackName:                   aws.String("sandbox-a"),
+				StackId:                     aws.String("arn:aws:cloudformation:us-east-1:account-id:stack/sandbox-a"),
+				StackStatus:                 aws.String(cloudformation.StackStatusCreateComplete),
+				EnableTerminationProtection: aws.Bool(false),
+			}
+			stackChan <- &cloudformation.Stack{
+				StackName:                   aws.String("sandbox-b"),
+				StackId:                     aws.String("arn:aws:cloudformation:eu-west-1:account-id:stack/sandbox-b"),
+				StackStatus:                 aws.String(cloudformation.StackStatusCreateComplete),
+				EnableTerminationProtection: aws.Bool(false),
+			}
+			stackChan <- &cloudformation.Stack{
+				StackName:                   aws.String("shared"),
+				StackId:                     aws.String("arn:aws:cloudformation:us-east-1:account-id:stack/shared"),
+				StackStatus:                 aws.String(cloudformation.StackStatusCreateComplete),
+				EnableTerminationProtection: aws.Bool(false),
+			}
+		},
+		deleteStub: func(stackArn *string) error {
+			deletedStacks = append(deletedStacks, *stackArn)
+			return nil
+		},
+		regionCount: 1,
+	}
+	localViper := viper.New()
+	localViper.Set("regions", []string{"us-east-1"})
+	localViper.Set("name", []string{"sandbox-*"})
+	ucmd := &deleteAllCmd{
+		cm: &CommandManagement{
+			cfnManager: mockCfnManager,
+			config:     &config{mode: noninteractive},
+			viper:      localViper,
+		},
+	}
+
+	// act
+	preErr := ucmd.preRunE(nil, nil)
+	err := ucmd.runE(nil, nil)
+
+	// assert
+	if preErr != nil || err != nil {
+		t.Errorf("Command delete-all failed. %v %v", preErr, err)
+	}
+	if len(deletedStacks) != 1 || deletedStacks[0] != "arn:aws:cloudformation:us-east-1:account-id:stack/sandbox-a" {
+		t.Errorf("Only the selected stack should be deleted. %#v", deletedStacks)
+	}
+}
+
+func TestDeleteAllCmdRunE_DryRun(t *testing.T) {
+	// arrange
+	deleted := false
+	mockCfnManager := &mockCfnManager{
+		getAllStub: func(stackChan chan *cloudformation.Stack, errChan chan error) {
+			stackChan <- &cloudformation.Stack{
+				StackName:                   aws.String("a"),
+				StackId:                     aws.String("arn:aws:cloudformation:us-east-1:account-id:stack/a"),
+				StackStatus:                 aws.String(cloudformation.StackStatusCreateComplete),
+				EnableTerminationProtection: aws.Bool(false),
+			}
+		},
+		deleteStub: func(stackArn *string) error {
+			deleted = true
+			return nil
+		},
+		regionCount: 1,
+	}
+	ucmd := &deleteAllCmd{
+		cm: &CommandManagement{
+			cfnManager: mockCfnManager,
+			config:     &config{mode: dry},
+		},
+	}
+
+	// act
+	err := ucmd.runE(nil, nil)
+
+	// assert
+	if err != nil || deleted {
+		t.Error("Dry run should not delete stacks.")
+	}
+}
+
+func TestDeleteAllCmdRunE_FailedConsumerKeepsProducer(t *testing.T) {
+	// arrange
+	deletedStacks := make([]string, 0)
+	mockCfnManager := dependencyManager(map[string][]string{
+		"vpc": {"app"},
+	})
+	mockCfnManager.getAllStub = func(stackChan chan *cloudformation.Stack, errChan chan error) {
+		stackChan <- testStack("vpc")
+		stackChan <- testStack("app")
+	}
+	mockCfnManager.deleteStub = func(stackArn *string) error {
+		deletedStacks = append(deletedStacks, *stackArn)
+		return nil
+	}
+	mockCfnManager.waitStackDeletedStub = func(stackArn *string) error {
+		return errors.New("DELETE_FAILED")
+	}
+	ucmd := &deleteAllCmd{
+		cm: &CommandManagement{
+			cfnManager: mockCfnManager,
+			config:     &config{mode: noninteractive},
+		},
+	}
+
+	// act
+	err := ucmd.runE(nil, nil)
+
+	// assert
+	if err == nil {
+		t.Error("Failed deletions should be reported.")
+	}
+	if len(deletedStacks) != 1 || deletedStacks[0] != *testStack("app").StackId {
+		t.Errorf("The exporting stack should be kept when its consumer failed. %#v", deletedStacks)
+	}
+}
+
+func TestDeleteAllCmdRunE_Summary(t *testing.T) {
+	// arrange
+	protected := testStack("protected")
+	protected.EnableTerminationProtection = aws.Bool(true)
+	mockCfnManager := &mockCfnManager{
+		getAllStub: func(stackChan chan *cloudformation.Stack, errChan chan error) {
+			stackChan <- testStack("a")
+			stackChan <- testStack("b")
+			stackChan <- testStack("c")
+			stackChan <- protected
+		},
+		waitStackDeletedStub: func(stackArn *string) error {
+			if *stackArn == *testStack("b").StackId {
+				return errors.New("DELETE_FAILED")
+			}
+			return nil
+		},
+		regionCount: 1,
+	}
+	ucmd := &deleteAllCmd{
+		cm: &CommandManagement{
+			cfnManager: mockCfnManager,
+			config:     &config{mode: noninteractive},
+		},
+		filter:      &stackFilter{names: []string{"a", "b"}},
+		concurrency: 2,
+	}
+
+	// act
+	outcomes := ucmd.deleteWave(context.Background(), &stackDeleter{cfnManager: mockCfnManager}, []*cloudformation.Stack{testStack("a"), testStack("b")})
+	err := ucmd.runE(nil, nil)
+
+	// assert
+	if len(outcomes) != 2 {
+		t.Errorf("Every stack of the wave should have an outcome. %#v", outcomes)
+	}
+	if err == nil || err.Error() != "Failed to delete 1 stack(s)." {
+		t.Errorf("Failed deletions should fail the command. %v", err)
+	}
+}
@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/spf13/viper"
+)
+
+func TestDeleteCmdPreRunE_ValidatesParams(t *testing.T) {
+	ucmd := &deleteCmd{
+		cm: &CommandManagement{
+			config: &config{mode: changesetonly},
+			viper:  viper.New(),
+		},
+	}
+
+	err := ucmd.preRunE(nil, nil)
+
+	if err == nil {
+		t.Error("Command delete parameters validation failed.")
+	}
+}
+
+func TestDeleteCmdRunE_DryRun(t *testing.T) {
+	// arrange
+	deleted := false
+	ucmd := &deleteCmd{
+		cm: &CommandManagement{
+			cfnManager: &mockCfnManager{
+				getStackStub: func(stackName *string) (*cloudformation.Stack, error) {
+					return testStack("a"), nil
+				},
+				deleteStub: func(stackName *string) error {
+					deleted = true
+					return nil
+				},
+			},
+			config: &config{mode: dry},
+		},
+		target: "a",
+	}
+
+	// act
+	err := ucmd.runE(nil, nil)
+
+	// assert
+	if err != nil || deleted {
+		t.Error("Dry run should not delete the stack.")
+	}
+}
+
+func TestDeleteCmdRunE_RetainsFailedResources(t *testing.T) {
+	// arrange
+	attempts := 0
+	failedStack := testStack("a")
+	failedStack.StackStatus = aws.String(cloudformation.StackStatusDeleteFailed)
+	mockCfnManager := &mockCfnManager{
+		getStackStub: func(stackName *string) (*cloudformation.Stack, error) {
+			return failedStack, nil
+		},
+		deleteStub: func(stackName *string) error {
+			attempts = attempts + 1
+			return nil
+		},
+		waitStackDeletedStub: func(stackName *string) error {
+			if attempts == 1 {
+				return errors.New("DELETE_FAILED")
+			}
+			return nil
+		},
+		listStackResourcesStub: func(stackName *string) ([]*cloudformation.StackResourceSummary, error) {
+			return []*cloudformation.StackResourceSummary{
+				&cloudformation.StackResourceSummary{
+					LogicalResourceId:  aws.String("Bucket"),
+					PhysicalResourceId: aws.String("bucket-name"),
+					ResourceType:       aws.String("AWS::S3::Bucket"),
+					ResourceStatus:     aws.String(cloudformation.ResourceStatusDeleteFailed),
+				},
+				&cloudformation.StackResourceSummary{
+					LogicalResourceId: aws.String("Queue"),
+					ResourceStatus:    aws.String(cloudformation.ResourceStatusDeleteComplete),
+				},
+			}, nil
+		},
+	}
+	ucmd := &deleteCmd{
+		cm: &CommandManagement{
+			cfnManager: mockCfnManager,
+			config:     &config{mode: noninteractive},
+		},
+		target:       "a",
+		retainFailed: true,
+	}
+
+	// act
+	err := ucmd.runE(nil, nil)
+
+	// assert
+	if err != nil {
+		t.Errorf("The retry should succeed. %v", err)
+	}
+	if attempts != 2 || len(mockCfnManager.retainResources) != 1 || *mockCfnManager.retainResources[0] != "Bucket" {
+		t.Errorf("The retry should retain the failed resource. %v %v", attempts, aws.StringValueSlice(mockCfnManager.retainResources))
+	}
+}
+
+func TestDeleteCmdRunE_RefusesProtectedStack(t *testing.T) {
+	// arrange
+	deleted := false
+	protected := testStack("a")
+	protected.EnableTerminationProtection = aws.Bool(true)
+	ucmd := &deleteCmd{
+		cm: &CommandManagement{
+			cfnManager: &mockCfnManager{
+				getStackStub: func(stackName *string) (*cloudformation.Stack, error) {
+					return protected, nil
+				},
+				deleteStub: func(stackName *string) error {
+					deleted = true
+					return nil
+				},
+			},
+			config: &config{mode: noninteractive},
+		},
+		target: "a",
+	}
+
+	// act
+	err := ucmd.runE(nil, nil)
+
+	// assert
+	if err == nil || deleted {
+		t.Error("Protected stacks should not be deleted without --force-unprotect.")
+	}
+}
+
+func TestDeleteCmdRunE_ForceUnprotect(t *testing.T) {
+	// arrange
+	calls := make([]string, 0)
+	protected := testStack("a")
+	protected.EnableTerminationProtection = aws.Bool(true)
+	ucmd := &deleteCmd{
+		cm: &CommandManagement{
+			cfnManager: &mockCfnManager{
+				getStackStub: func(stackName *string) (*cloudformation.Stack, error) {
+					return protected, nil
+				},
+				setProtectionStub: func(stackName *string, enabled bool) error {
+					if !enabled {
+						calls = append(calls, "unprotect")
+					}
+					return nil
+				},
+				deleteStub: func(stackName *string) error {
+					calls = append(calls, "delete")
+					return nil
+				},
+			},
+			config: &config{mode: noninteractive},
+		},
+		target:         "a",
+		forceUnprotect: true,
+	}
+
+	// act
+	err := ucmd.runE(nil, nil)
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 2 || calls[0] != "unprotect" || calls[1] != "delete" {
+		t.Errorf("Termination protection should be disabled before the deletion. %#v", calls)
+	}
+}
+
+func TestDeleteCmdRunE_ForceUnprotectRestoredOnFailure(t *testing.T) {
+	// arrange
+	calls := make([]string, 0)
+	protected := testStack("a")
+	protected.EnableTerminationProtection = aws.Bool(true)
+	ucmd := &deleteCmd{
+		cm: &CommandManagement{
+			cfnManager: &mockCfnManager{
+				getStackStub: func(stackName *string) (*cloudformation.Stack, error) {
+					return protected, nil
+				},
+				setProtectionStub: func(stackName *string, enabled bool) error {
+					calls = append(calls, fmt.Sprintf("protect %v", enabled))
+					return nil
+				},
+				deleteStub: func(stackName *string) error {
+					calls = append(calls, "delete")
+					return errors.New("AccessDenied")
+				},
+			},
+			config: &config{mode: noninteractive},
+		},
+		target:         "a",
+		forceUnprotect: true,
+	}
+
+	// act
+	err := ucmd.runE(nil, nil)
+
+	// assert
+	if err == nil {
+		t.Fatal("A failed deletion should fail the command.")
+	}
+	if strings.Join(calls, ", ") != "protect false, delete, protect true" {
+		t.Errorf("Termination protection should be restored when the deletion fails. %#v", calls)
+	}
+}
+
+func TestPrintStackResources(t *testing.T) {
+	// arrange
+	out := &bytes.Buffer{}
+
+	// act
+	printStackResources(out, []*cloudformation.StackResourceSummary{
+		&cloudformation.StackResourceSummary{
+			LogicalResourceId:  aws.String("Bucket"),
+			ResourceType:       aws.String("AWS::S3::Bucket"),
+			PhysicalResourceId: aws.String("bucket-name"),
+			ResourceStatus:     aws.String(cloudformation.ResourceStatusDeleteComplete),
+		},
+	})
+
+	// assert
+	if !strings.Contains(out.String(), "DELETE_COMPLETE  Bucket") || !strings.Contains(out.String(), "bucket-name") {
+		t.Errorf("Resources are not rendered.\n%v", out.String())
+	}
+}
@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+)
+
+// deletionPlan orders stacks so that a stack importing an export is deleted before the stack exporting it.
+// Each wave only contains stacks whose importers were deleted in earlier waves.
+type deletionPlan struct {
+	waves       [][]*cloudformation.Stack
+	skipped     map[string]string   // stack id to the reason it is not deleted
+	importsFrom map[string][]string // stack id to the ids of the stacks it imports from
+	names       map[string]string   // stack id to stack name
+}
+
+// planDeletion builds the deletion waves of the selected stacks. allStacks are all stacks of the accounts and regions involved,
+// used to resolve nested stacks and the importers that are not selected.
+// Nested stacks are deleted with their root stack. Exports and imports of nested stacks count as their root's.
+func (cm *CommandManagement) planDeletion(ctx context.Context, allStacks []*cloudformation.Stack, selected []*cloudformation.Stack) (*deletionPlan, error) {
+	plan := &deletionPlan{
+		skipped:     make(map[string]string),
+		importsFrom: make(map[string][]string),
+		names:       make(map[string]string),
+	}
+
+	byId := make(map[string]*cloudformation.Stack)
+	idsByLocation := make(map[stackLocation]map[string]string) // account and region to stack name to stack id
+	for _, stack := range allStacks {
+		location := locateStack(stack.StackId)
+		if location.region == "" {
+			continue
+		}
+		id := aws.StringValue(stack.StackId)
+		byId[id] = stack
+		plan.names[id] = aws.StringValue(stack.StackName)
+		if idsByLocation[location] == nil {
+			idsByLocation[location] = make(map[string]string)
+		}
+		idsByLocation[location][aws.StringValue(stack.StackName)] = id
+	}
+	rootOf := func(id string) string {
+		if stack, exist := byId[id]; exist && stack.RootId != nil {
+			return aws.StringValue(stack.RootId)
+		}
+		return id
+	}
+
+	selectedIds := make(map[string]bool)
+	for _, stack := range selected {
+		selectedIds[aws.StringValue(stack.StackId)] = true
+		byId[aws.StringValue(stack.StackId)] = stack
+	}
+
+	pending := make(map[string]bool)
+	order := make([]string, 0, len(selected))
+	locations := make([]stackLocation, 0)
+	for _, stack := range selected {
+		id := aws.StringValue(stack.StackId)
+		location := locateStack(stack.StackId)
+		if location.region == "" {
+			plan.skipped[id] = "invalid stack id"
+			continue
+		}
+		if stack.RootId != nil {
+			if !selectedIds[aws.StringValue(stack.RootId)] {
+				plan.skipped[id] = "nested in " + plan.name(aws.StringValue(stack.RootId))
+			}
+			continue
+		}
+		pending[id] = true
+		order = append(order, id)
+		if !containsLocation(locations, location) {
+			locations = append(locations, location)
+		}
+	}
+
+	// Exports are per account and region, so are the dependencies.
+	importedBy := make(map[string][]string)
+	for _, location := range locations {
+		accountManager, accountErr := cm.cfnManager.inAccount(location.accountId)
+		if accountErr != nil {
+			return nil, accountErr
+		}
+		regionManager := accountManager.inRegion(location.region)
+		exports, exportsErr := regionManager.listExports(ctx)
+		if exportsErr != nil {
+			return nil, errors.New(fmt.Sprintf("Unable to list exports in %v: %v", location, exportsErr))
+		}
+
+		for _, export := range exports {
+			producer := rootOf(aws.StringValue(export.ExportingStackId))
+			if !pending[producer] || locateStack(aws.String(producer)) != location {
+				continue
+			}
+
+			importers, importsErr := regionManager.listImports(ctx, export.Name)
+			if importsErr != nil {
+				return nil, errors.New(fmt.Sprintf("Unable to list imports of %v in %v: %v", aws.StringValue(export.Name), location, importsErr))
+			}
+			for _, importer := range importers {
+				consumer, exist := idsByLocation[location][aws.StringValue(importer)]
+				if !exist {
+					consumer = aws.StringValue(importer)
+				}
+				consumer = rootOf(consumer)
+				if consumer == producer {
+					continue
+				}
+				importedBy[producer] = append(importedBy[producer], consumer)
+				plan.importsFrom[consumer] = append(plan.importsFrom[consumer], producer)
+			}
+		}
+	}
+
+	// A stack that stays keeps the stacks it imports from.
+	for changed := true; changed; {
+		changed = false
+		for _, id := range order {
+			if !pending[id] {
+				continue
+			}
+			for _, consumer := range importedBy[id] {
+				if !pending[consumer] {
+					plan.skipped[id] = "export imported by " + plan.name(consumer)
+					delete(pending, id)
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	for len(pending) > 0 {
+		wave := make([]*cloudformation.Stack, 0)
+		for _, id := range order {
+			if !pending[id] {
+				continue
+			}
+			ready := true
+			for _, consumer := range importedBy[id] {
+				if pending[consumer] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				wave = append(wave, byId[id])
+			}
+		}
+
+		if len(wave) == 0 {
+			for id := range pending {
+				plan.skipped[id] = "circular imports"
+			}
+			break
+		}
+		for _, stack := range wave {
+			delete(pending, aws.StringValue(stack.StackId))
+		}
+		plan.waves = append(plan.waves, wave)
+	}
+
+	return plan, nil
+}
+
+// block skips the stacks a stack that failed to delete imports from, and in turn the stacks those import from.
+func (p *deletionPlan) block(id string, reason string) {
+	for _, producer := range p.importsFrom[id] {
+		if _, skipped := p.skipped[producer]; skipped {
+			continue
+		}
+		p.skipped[producer] = reason
+		p.block(producer, reason)
+	}
+}
+
+func (p *deletionPlan) name(id string) string {
+	if name, exist := p.names[id]; exist {
+		return name
+	}
+	return id
+}
+
+func containsLocation(locations []stackLocation, location stackLocation) bool {
+	for _, candidate := range locations {
+		if candidate == location {
+			return true
+		}
+	}
+	return false
+}
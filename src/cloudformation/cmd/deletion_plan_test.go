@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+)
+
+func testStack(name string) *cloudformation.Stack {
+	return &cloudformation.Stack{
+		StackName:   aws.String(name),
+		StackId:     aws.String("arn:aws:cloudformation:us-east-1:account-id:stack/" + name),
+		StackStatus: aws.String(cloudformation.StackStatusCreateComplete),
+	}
+}
+
+// dependencyManager returns a mock where every stack exports its name and imports are given by importer name.
+func dependencyManager(imports map[string][]string) *mockCfnManager {
+	return &mockCfnManager{
+		listExportsStub: func() ([]*cloudformation.Export, error) {
+			exports := make([]*cloudformation.Export, 0)
+			for exporter := range imports {
+				exports = append(exports, &cloudformation.Export{
+					Name:             aws.String(exporter),
+					ExportingStackId: testStack(exporter).StackId,
+				})
+			}
+			return exports, nil
+		},
+		listImportsStub: func(exportName *string) ([]*string, error) {
+			return aws.StringSlice(imports[*exportName]), nil
+		},
+		regionCount: 1,
+	}
+}
+
+func TestPlanDeletion_ConsumersFirst(t *testing.T) {
+	// arrange
+	vpc, db, app := testStack("vpc"), testStack("db"), testStack("app")
+	cm := &CommandManagement{
+		cfnManager: dependencyManager(map[string][]string{
+			"vpc": {"db", "app"},
+			"db":  {"app"},
+		}),
+	}
+	stacks := []*cloudformation.Stack{vpc, db, app}
+
+	// act
+	plan, err := cm.planDeletion(context.Background(), stacks, stacks)
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.waves) != 3 || plan.waves[0][0] != app || plan.waves[1][0] != db || plan.waves[2][0] != vpc {
+		t.Errorf("Stacks should be deleted consumers first. %#v", plan.waves)
+	}
+}
+
+func TestPlanDeletion_KeepsExportersOfKeptStacks(t *testing.T) {
+	// arrange
+	vpc, db, app := testStack("vpc"), testStack("db"), testStack("app")
+	cm := &CommandManagement{
+		cfnManager: dependencyManager(map[string][]string{
+			"vpc": {"db"},
+			"db":  {"app"},
+		}),
+	}
+
+	// act
+	plan, err := cm.planDeletion(context.Background(), []*cloudformation.Stack{vpc, db, app}, []*cloudformation.Stack{vpc, db})
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.waves) != 0 {
+		t.Errorf("Nothing can be deleted while app stays. %#v", plan.waves)
+	}
+	if plan.skipped[*db.StackId] != "export imported by app" || plan.skipped[*vpc.StackId] != "export imported by db" {
+		t.Errorf("Skip reasons are wrong. %#v", plan.skipped)
+	}
+}
+
+func TestPlanDeletion_NestedStacks(t *testing.T) {
+	// arrange
+	root, orphanRoot := testStack("root"), testStack("other")
+	nested := testStack("root-nested")
+	nested.RootId = root.StackId
+	nested.ParentId = root.StackId
+	orphan := testStack("other-nested")
+	orphan.RootId = orphanRoot.StackId
+	cm := &CommandManagement{
+		cfnManager: &mockCfnManager{},
+	}
+
+	// act
+	plan, err := cm.planDeletion(context.Background(), []*cloudformation.Stack{root, nested, orphanRoot, orphan}, []*cloudformation.Stack{root, nested, orphan})
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.waves) != 1 || len(plan.waves[0]) != 1 || plan.waves[0][0] != root {
+		t.Errorf("Nested stacks should be deleted with their root. %#v", plan.waves)
+	}
+	if plan.skipped[*orphan.StackId] != "nested in other" {
+		t.Errorf("Nested stacks of kept roots should be skipped. %#v", plan.skipped)
+	}
+}
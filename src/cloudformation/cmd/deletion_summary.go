@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+)
+
+// Results of a stack in a bulk deletion.
+const (
+	resultDeleted   = "DELETED"
+	resultFailed    = "FAILED"
+	resultSkipped   = "SKIPPED"
+	resultProtected = "PROTECTED"
+	resultFiltered  = "FILTERED"
+)
+
+var deletionResults = []string{resultDeleted, resultFailed, resultSkipped, resultProtected, resultFiltered}
+
+// deletionOutcome is what happened to a stack in a bulk deletion.
+type deletionOutcome struct {
+	stack  *cloudformation.Stack
+	result string
+	reason string
+}
+
+// deletionSummary collects the outcomes of a bulk deletion.
+type deletionSummary struct {
+	outcomes []*deletionOutcome
+}
+
+func (s *deletionSummary) add(stack *cloudformation.Stack, result string, reason string) {
+	s.outcomes = append(s.outcomes, &deletionOutcome{
+		stack:  stack,
+		result: result,
+		reason: reason,
+	})
+}
+
+func (s *deletionSummary) count(result string) int {
+	count := 0
+	for _, outcome := range s.outcomes {
+		if outcome.result == result {
+			count = count + 1
+		}
+	}
+	return count
+}
+
+// print renders the outcomes grouped by result, followed by the count of each result.
+func (s *deletionSummary) print(out io.Writer) {
+	fmt.Fprintln(out, "Summary:")
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "  RESULT\tSTACK\tACCOUNT\tREGION\tREASON")
+	for _, result := range deletionResults {
+		for _, outcome := range s.outcomes {
+			if outcome.result != result {
+				continue
+			}
+			location := locateStack(outcome.stack.StackId)
+			fmt.Fprintf(tw, "  %v\t%v\t%v\t%v\t%v\n",
+				outcome.result,
+				aws.StringValue(outcome.stack.StackName),
+				orDash(location.accountId),
+				orDash(location.region),
+				orDash(outcome.reason))
+		}
+	}
+	tw.Flush()
+
+	fmt.Fprintf(out, "%v deleted, %v failed, %v skipped, %v protected, %v filtered out.\n",
+		s.count(resultDeleted),
+		s.count(resultFailed),
+		s.count(resultSkipped),
+		s.count(resultProtected),
+		s.count(resultFiltered))
+}
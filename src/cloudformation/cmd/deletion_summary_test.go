@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDeletionSummaryPrint(t *testing.T) {
+	// arrange
+	summary := &deletionSummary{}
+	summary.add(testStack("kept"), resultFiltered, "name")
+	summary.add(testStack("broken"), resultFailed, "bucket not empty")
+	summary.add(testStack("gone"), resultDeleted, "")
+	out := &bytes.Buffer{}
+
+	// act
+	summary.print(out)
+
+	// assert
+	lines := strings.Split(out.String(), "\n")
+	if !strings.Contains(lines[2], "DELETED") || !strings.Contains(lines[3], "bucket not empty") || !strings.Contains(lines[4], "FILTERED") {
+		t.Errorf("Outcomes should be grouped by result.\n%v", out.String())
+	}
+	if !strings.Contains(out.String(), "1 deleted, 1 failed, 0 skipped, 0 protected, 1 filtered out.") {
+		t.Errorf("Counts are wrong.\n%v", out.String())
+	}
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"strings"
+)
+
+// driftError is returned when drift was detected so scheduled runs can alert on the exit code.
+type driftError struct {
+	driftedStacks []string
+}
+
+func (e *driftError) Error() string {
+	return fmt.Sprintf("Drift detected in %v stack(s): %v", len(e.driftedStacks), strings.Join(e.driftedStacks, ", "))
+}
+
+func (e *driftError) ExitCode() int {
+	return exitDrifted
+}
+
+type driftCmd struct {
+	target string
+	all    bool
+	cm     *CommandManagement
+	cmd    *cobra.Command
+}
+
+func (uc *driftCmd) runE(cmd *cobra.Command, args []string) error {
+
+	cfnManager := uc.cm.cfnManager
+	ctx := uc.cm.context()
+
+	// Stacks to check. Regions that cannot be listed are reported at the end.
+	stackNames := []string{uc.target}
+	var listErr error
+	if uc.all {
+		var stacks []*cloudformation.Stack
+		stacks, listErr = uc.cm.collectStacks(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		stackNames = make([]string, 0, len(stacks))
+		for _, stack := range stacks {
+			if strings.HasSuffix(aws.StringValue(stack.StackStatus), "_IN_PROGRESS") {
+				fmt.Printf("Skipping stack in progress: %v (%v - %v)\n", aws.StringValue(stack.StackName), locateStack(stack.StackId), aws.StringValue(stack.StackStatus))
+				continue
+			}
+			stackNames = append(stackNames, aws.StringValue(stack.StackId))
+		}
+	}
+
+	drifted := make([]string, 0)
+	failed := make([]string, 0)
+	for _, stackName := range stackNames {
+		drift, driftErr := cfnManager.detectDrift(ctx, aws.String(stackName))
+		if driftErr != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			fmt.Printf("Unable to detect drift of %v: %v\n", stackName, driftErr)
+			failed = append(failed, stackName)
+			continue
+		}
+
+		printDriftReport(os.Stdout, stackName, drift)
+		if len(drift.resources) > 0 {
+			drifted = append(drifted, stackName)
+		}
+	}
+
+	if len(failed) > 0 {
+		return errors.New(fmt.Sprintf("Drift detection failed for %v stack(s): %v", len(failed), strings.Join(failed, ", ")))
+	}
+	if listErr != nil {
+		return listErr
+	}
+	if len(drifted) > 0 {
+		return &driftError{driftedStacks: drifted}
+	}
+	return nil
+}
+
+// printDriftReport prints each drifted resource with its expected and actual property values.
+func printDriftReport(out io.Writer, stackName string, drift *stackDrift) {
+	fmt.Fprintf(out, "Stack: %v (%v)\n", stackName, orDash(aws.StringValue(drift.status.StackDriftStatus)))
+	if reason := aws.StringValue(drift.status.DetectionStatusReason); reason != "" {
+		fmt.Fprintf(out, "  Detection status: %v - %v\n", aws.StringValue(drift.status.DetectionStatus), reason)
+	}
+
+	for _, resource := range drift.resources {
+		fmt.Fprintf(out, "  %v %v (%v) %v\n",
+			aws.StringValue(resource.StackResourceDriftStatus),
+			aws.StringValue(resource.LogicalResourceId),
+			aws.StringValue(resource.ResourceType),
+			orDash(aws.StringValue(resource.PhysicalResourceId)))
+		for _, difference := range resource.PropertyDifferences {
+			fmt.Fprintf(out, "    %v %v\n", driftMarker(aws.StringValue(difference.DifferenceType)), aws.StringValue(difference.PropertyPath))
+			fmt.Fprintf(out, "        expected: %v\n", aws.StringValue(difference.ExpectedValue))
+			fmt.Fprintf(out, "        actual:   %v\n", aws.StringValue(difference.ActualValue))
+		}
+	}
+}
+
+func driftMarker(differenceType string) string {
+	switch differenceType {
+	case cloudformation.DifferenceTypeAdd:
+		return "+"
+	case cloudformation.DifferenceTypeRemove:
+		return "-"
+	default:
+		return "~"
+	}
+}
+
+func (uc *driftCmd) preRunE(cmd *cobra.Command, args []string) error {
+
+	localViper := uc.cm.viper
+	uc.target = localViper.GetString("target")
+	uc.all = localViper.GetBool("all")
+
+	// parameter validations
+	var errstrings []string
+	if uc.target == "" && !uc.all {
+		errstrings = append(errstrings, "Please specify target stack or --all.")
+	}
+	if uc.target != "" && uc.all {
+		errstrings = append(errstrings, "Please specify either target stack or --all, not both.")
+	}
+
+	if len(errstrings) > 0 {
+		return errors.New(strings.Join(errstrings, "\n"))
+	}
+
+	return nil
+}
+
+var driftCmdLong = `Detect drift on a stack, or on all stacks in the selected regions and accounts, and report the property level differences. Exits with code 4 when drift is found.`
+
+func (cm *CommandManagement) initDriftCmd() {
+
+	// init command structure
+	cmd := &cobra.Command{
+		Use:   "drift",
+		Short: "drift",
+		Long:  driftCmdLong,
+	}
+	ucmd := &driftCmd{
+		cm:  cm,
+		cmd: cmd,
+	}
+
+	// local params
+	cmd.Flags().StringP("target", "t", "", "Stack name or arn to check")
+	cmd.Flags().Bool("all", false, "Check all stacks in the selected regions and accounts")
+
+	// wire methods.
+	cmd.PreRunE = ucmd.preRunE
+	cmd.RunE = ucmd.runE
+
+	// register
+	cm.root.AddCommand(cmd)
+}
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/spf13/viper"
+)
+
+func TestDriftCmdPreRunE_ValidatesParams(t *testing.T) {
+	ucmd := &driftCmd{
+		cm: &CommandManagement{
+			viper: viper.New(),
+		},
+	}
+
+	err := ucmd.preRunE(nil, nil)
+
+	if err == nil {
+		t.Error("Command drift parameters validation failed.")
+	}
+}
+
+func TestDriftCmdRunE_AllReportsDrift(t *testing.T) {
+	// arrange
+	checked := make([]string, 0)
+	mockCfnManager := &mockCfnManager{
+		getAllStub: func(stackChan chan *cloudformation.Stack, errChan chan error) {
+			stackChan <- &cloudformation.Stack{
+				StackName:   aws.String("a"),
+				StackId:     aws.String("arn:aws:cloudformation:region:account-id:stack/a"),
+				StackStatus: aws.String(cloudformation.StackStatusCreateComplete),
+			}
+			stackChan <- &cloudformation.Stack{
+				StackName:   aws.String("b"),
+				StackId:     aws.String("arn:aws:cloudformation:region:account-id:stack/b"),
+				StackStatus: aws.String(cloudformation.StackStatusUpdateInProgress),
+			}
+		},
+		detectDriftStub: func(stackName *string) (*stackDrift, error) {
+			checked = append(checked, *stackName)
+			return &stackDrift{
+				status: &cloudformation.DescribeStackDriftDetectionStatusOutput{
+					StackDriftStatus: aws.String(cloudformation.StackDriftStatusDrifted),
+				},
+				resources: []*cloudformation.StackResourceDrift{
+					&cloudformation.StackResourceDrift{
+						LogicalResourceId:        aws.String("Bucket"),
+						StackResourceDriftStatus: aws.String(cloudformation.StackResourceDriftStatusModified),
+					},
+				},
+			}, nil
+		},
+		regionCount: 1,
+	}
+	ucmd := &driftCmd{
+		cm: &CommandManagement{
+			cfnManager: mockCfnManager,
+			config:     &config{mode: noninteractive},
+		},
+		all: true,
+	}
+
+	// act
+	err := ucmd.runE(nil, nil)
+
+	// assert
+	if len(checked) != 1 {
+		t.Errorf("Only settled stacks should be checked. %#v", checked)
+	}
+	if ExitCode(err) != exitDrifted {
+		t.Errorf("Drift should exit with its own code. %v", err)
+	}
+}
+
+func TestDriftCmdRunE_NoDrift(t *testing.T) {
+	// arrange
+	ucmd := &driftCmd{
+		cm: &CommandManagement{
+			cfnManager: &mockCfnManager{},
+			config:     &config{mode: noninteractive},
+		},
+		target: "a",
+	}
+
+	// act
+	err := ucmd.runE(nil, nil)
+
+	// assert
+	if err != nil {
+		t.Errorf("No drift should not fail. %v", err)
+	}
+}
+
+func TestDriftCmdRunE_DetectionFailure(t *testing.T) {
+	// arrange
+	ucmd := &driftCmd{
+		cm: &CommandManagement{
+			cfnManager: &mockCfnManager{
+				detectDriftStub: func(stackName *string) (*stackDrift, error) {
+					return nil, errors.New("throttled")
+				},
+			},
+			config: &config{mode: noninteractive},
+		},
+		target: "a",
+	}
+
+	// act
+	err := ucmd.runE(nil, nil)
+
+	// assert
+	if err == nil || ExitCode(err) != exitError {
+		t.Error("Failed detections should be reported as errors.")
+	}
+}
+
+func TestPrintDriftReport_PropertyDifferences(t *testing.T) {
+	// arrange
+	drift := &stackDrift{
+		status: &cloudformation.DescribeStackDriftDetectionStatusOutput{
+			StackDriftStatus: aws.String(cloudformation.StackDriftStatusDrifted),
+		},
+		resources: []*cloudformation.StackResourceDrift{
+			&cloudformation.StackResourceDrift{
+				LogicalResourceId:        aws.String("Queue"),
+				ResourceType:             aws.String("AWS::SQS::Queue"),
+				StackResourceDriftStatus: aws.String(cloudformation.StackResourceDriftStatusModified),
+				PropertyDifferences: []*cloudformation.PropertyDifference{
+					&cloudformation.PropertyDifference{
+						PropertyPath:   aws.String("/VisibilityTimeout"),
+						DifferenceType: aws.String(cloudformation.DifferenceTypeNotEqual),
+						ExpectedValue:  aws.String("30"),
+						ActualValue:    aws.String("60"),
+					},
+				},
+			},
+		},
+	}
+	out := &bytes.Buffer{}
+
+	// act
+	printDriftReport(out, "stack", drift)
+
+	// assert
+	for _, expected := range []string{"DRIFTED", "MODIFIED Queue", "~ /VisibilityTimeout", "expected: 30", "actual:   60"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Drift report is missing %#v.\n%v", expected, out.String())
+		}
+	}
+}
@@ -1,27 +1,73 @@
 package cmd
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/cloudformation"
 	"github.com/spf13/cobra"
-	"io/ioutil"
 	"strings"
 )
 
 type ensureCmd struct {
-	target       string
-	params       map[string]string
-	tags         map[string]string
-	templatePath string
-	cm           *CommandManagement
-	cmd          *cobra.Command
+	target         string
+	params         map[string]string
+	tags           map[string]string
+	templatePath   string
+	templateURL    string
+	templateBucket string
+	artifactBucket string
+	options        *changeSetOptions
+	protect        bool
+	cm             *CommandManagement
+	cmd            *cobra.Command
 }
 
 func (uc *ensureCmd) runE(cmd *cobra.Command, args []string) error {
 
-	cfnManager := uc.cm.cfnManager
+	ctx := uc.cm.context()
+	accountIds := uc.cm.cfnManager.accountIds()
+	if len(accountIds) == 0 {
+		return uc.ensure(ctx, uc.cm)
+	}
+
+	// Ensure the stack in every region of every account, through a copy of the command manager bound to each.
+	// Output is prefixed with the account and region, the stack name being the same in all of them.
+	failed := make([]string, 0)
+	for _, accountId := range accountIds {
+		accountManager, accountErr := uc.cm.cfnManager.inAccount(accountId)
+		if accountErr != nil {
+			return accountErr
+		}
+		for _, region := range accountManager.getDeployRegions() {
+			location := stackLocation{accountId: accountId, region: region}
+			fmt.Fprintf(uc.cm.output(), "Ensuring stack %v in %v\n", uc.target, location)
+			targetCm := *uc.cm
+			targetCm.cfnManager = accountManager.inRegion(region)
+			targetCm.out = &prefixWriter{out: uc.cm.output(), prefix: location.String() + ": "}
+			if ensureErr := uc.ensure(ctx, &targetCm); ensureErr != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				fmt.Fprintf(uc.cm.output(), "Unable to ensure stack %v in %v: %v\n", uc.target, location, ensureErr)
+				failed = append(failed, location.String())
+			}
+		}
+	}
 
-	stack, stackErr := cfnManager.getStack(&uc.target)
+	if len(failed) > 0 {
+		return errors.New(fmt.Sprintf("Failed to ensure stack %v in %v account region(s): %v", uc.target, len(failed), strings.Join(failed, ", ")))
+	}
+	return nil
+}
+
+// ensure creates or updates the target stack through the cfn manager of cm.
+func (uc *ensureCmd) ensure(ctx context.Context, cm *CommandManagement) error {
+
+	cfnManager := cm.cfnManager
+
+	stack, stackErr := cfnManager.getStack(ctx, &uc.target)
 	if stackErr != nil {
 		return stackErr
 	}
@@ -34,32 +80,18 @@ func (uc *ensureCmd) runE(cmd *cobra.Command, args []string) error {
 	}
 
 	// Get template first.
-	var templateString string
-	if len(uc.templatePath) > 0 {
-		// template specified.
-		buffer, templateReadErr := ioutil.ReadFile(uc.templatePath)
-		if templateReadErr != nil {
-			return templateReadErr
-		} else {
-			templateString = string(buffer)
-		}
-	} else if stack != nil {
-		// template not specified
-		// stack found
-		stackTemplate, stackTemplateErr := cfnManager.getStackTemplate(&uc.target)
-		if stackTemplateErr != nil {
-			return stackTemplateErr
-		}
-		templateString = *stackTemplate
-	} else {
-		//template not specified
-		// stack not found
-
-		return errors.New("No cloudformation template specified and no stack found. Cannot proceed.")
+	template, templateErr := cm.loadTemplate(ctx, &templateSource{
+		path:           uc.templatePath,
+		url:            uc.templateURL,
+		bucket:         uc.templateBucket,
+		artifactBucket: uc.artifactBucket,
+	}, &uc.target, stack != nil)
+	if templateErr != nil {
+		return templateErr
 	}
 
 	// Parameters
-	stackParams, spErr := uc.cm.filterParameters(&templateString, &uc.params, stack != nil)
+	stackParams, spErr := cm.filterParameters(ctx, template, &uc.params, stack != nil)
 	if spErr != nil {
 		return spErr
 	}
@@ -69,9 +101,37 @@ func (uc *ensureCmd) runE(cmd *cobra.Command, args []string) error {
 	if stack != nil {
 		oldTags = stack.Tags
 	}
-	stackTags := uc.cm.mergeTags(oldTags, &uc.tags)
+	stackTags := cm.mergeTags(oldTags, &uc.tags)
 
-	return uc.cm.createAndExecute(&uc.target, stackParams, stackTags, &templateString, csType)
+	executed, executeErr := cm.createAndExecute(ctx, &uc.target, stackParams, stackTags, template, csType, uc.options)
+	if executeErr != nil {
+		return executeErr
+	}
+	// Stacks are only protected along with a change set that executed, never one that was declined or left for review.
+	if uc.protect && executed {
+		return uc.protectStack(ctx, cm)
+	}
+	if uc.protect {
+		fmt.Println("Termination protection was not enabled: the change set was not executed.")
+	}
+	return nil
+}
+
+// protectStack enables termination protection on the target once its change set executed.
+func (uc *ensureCmd) protectStack(ctx context.Context, cm *CommandManagement) error {
+
+	stack, stackErr := cm.cfnManager.getStack(ctx, &uc.target)
+	if stackErr != nil {
+		return stackErr
+	}
+	if stack == nil || aws.BoolValue(stack.EnableTerminationProtection) {
+		return nil
+	}
+	if protectErr := cm.cfnManager.setTerminationProtection(ctx, stack.StackId, true); protectErr != nil {
+		return protectErr
+	}
+	fmt.Printf("Termination protection enabled on %v.\n", uc.target)
+	return nil
 }
 
 func (uc *ensureCmd) preRunE(cmd *cobra.Command, args []string) error {
@@ -81,15 +141,26 @@ func (uc *ensureCmd) preRunE(cmd *cobra.Command, args []string) error {
 	uc.params = localViper.GetStringMapString("param")
 	uc.tags = localViper.GetStringMapString("tag")
 	uc.templatePath = localViper.GetString("template-path")
+	uc.templateURL = localViper.GetString("template-url")
+	uc.templateBucket = localViper.GetString("template-bucket")
+	uc.artifactBucket = localViper.GetString("artifact-bucket")
+	options, errstrings := readChangeSetOptions(localViper)
+	uc.options = options
+	uc.protect = localViper.GetBool("protect")
 
 	// parameter validations
-	var errstrings []string
 	if uc.target == "" {
 		errstrings = append(errstrings, "Please specify target stack to update.")
 	}
-	if len(uc.params) == 0 && len(uc.tags) == 0 && uc.templatePath == "" {
+	if len(uc.params) == 0 && len(uc.tags) == 0 && uc.templatePath == "" && uc.templateURL == "" && options.empty() {
 		errstrings = append(errstrings, "Nothing specified to update.")
 	}
+	if uc.templatePath != "" && uc.templateURL != "" {
+		errstrings = append(errstrings, "Please specify either template-path or template-url, not both.")
+	}
+	if uc.artifactBucket != "" && uc.templatePath == "" {
+		errstrings = append(errstrings, "Please specify template-path to package artifacts from.")
+	}
 
 	if len(errstrings) > 0 {
 		return errors.New(strings.Join(errstrings, "\n"))
@@ -98,7 +169,9 @@ func (uc *ensureCmd) preRunE(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-var ensureCmdLong = `Create a cloudformation stack if the specified stack does not exist. Otherwise, perform an update.`
+var ensureCmdLong = `Create a cloudformation stack if the specified stack does not exist. Otherwise, perform an update.
+With an accounts file, the stack is ensured in every account, in the regions listed for the account,
+else in the regions given with --regions, else in the session's region.`
 
 func (cm *CommandManagement) initEnsureCmd() {
 
@@ -118,6 +191,11 @@ func (cm *CommandManagement) initEnsureCmd() {
 	cmd.Flags().StringToStringP("param", "p", nil, "Parameters to override")
 	cmd.Flags().StringToStringP("tag", "g", nil, "Parameters to override")
 	cmd.Flags().String("template-path", "", "Parameters to override")
+	cmd.Flags().String("template-url", "", "S3 url of an already hosted template to use instead of template-path")
+	cmd.Flags().String("template-bucket", "", "S3 bucket to stage the template in. Required for templates over 51200 bytes")
+	cmd.Flags().String("artifact-bucket", "", "S3 bucket to upload local artifacts referenced by the template to")
+	addChangeSetFlags(cmd)
+	cmd.Flags().Bool("protect", false, "Enable termination protection on the stack once it is created or updated")
 
 	// wire methods.
 	cmd.PreRunE = ucmd.preRunE
@@ -1,7 +1,10 @@
 package cmd
 
 import (
+	"bytes"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"strings"
 	"testing"
 
 	"path"
@@ -67,3 +70,134 @@ func TestEnsureCmdRunE_UpdateSuccess(t *testing.T) {
 	}
 
 }
+
+func TestEnsureCmdRunE_Accounts(t *testing.T) {
+
+	// arrange
+	var out bytes.Buffer
+	ensured := make([]string, 0)
+	accountManager := func(accountId string) cfnManagement {
+		return &mockCfnManager{
+			deployRegions: []string{"us-east-1", "eu-west-1"},
+			inRegionStub: func(region string) cfnManagement {
+				return &mockCfnManager{
+					createChangeSetStub: func(stackName *string, params []*cloudformation.Parameter, tags []*cloudformation.Tag, template *cfnTemplate, changeSetType string) (*cloudformation.CreateChangeSetOutput, error) {
+						ensured = append(ensured, accountId+"/"+region)
+						return &cloudformation.CreateChangeSetOutput{}, nil
+					},
+				}
+			},
+		}
+	}
+	ucmd := &ensureCmd{
+		cm: &CommandManagement{
+			cfnManager: &mockCfnManager{
+				accounts: []*account{
+					&account{AccountId: "111111111111"},
+					&account{AccountId: "222222222222"},
+				},
+				inAccountStub: accountManager,
+			},
+			config: &config{mode: noninteractive},
+			out:    &out,
+		},
+		target: "stack",
+	}
+
+	// act
+	err := ucmd.runE(nil, nil)
+
+	// assert
+	if err != nil {
+		t.Error(err)
+	}
+	if len(ensured) != 4 || ensured[0] != "111111111111/us-east-1" || ensured[3] != "222222222222/eu-west-1" {
+		t.Errorf("The stack should be ensured in every region of every account. %v", ensured)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if !strings.HasPrefix(line, "Ensuring stack") && !strings.HasPrefix(line, "111111111111/") && !strings.HasPrefix(line, "222222222222/") {
+			t.Errorf("Every line should tell the account and region of the stack. %q", line)
+		}
+	}
+	if !strings.Contains(out.String(), "222222222222/eu-west-1: Change set:") {
+		t.Errorf("The change set preview should be prefixed. %v", out.String())
+	}
+}
+
+func TestEnsureCmdRunE_Protect(t *testing.T) {
+
+	// arrange
+	var protectedStack *string
+	mockCfnManager := &mockCfnManager{
+		getStackStub: func(stackName *string) (*cloudformation.Stack, error) {
+			return &cloudformation.Stack{
+				StackId:                     aws.String("arn:aws:cloudformation:us-east-1:111111111111:stack/prod/1"),
+				StackStatus:                 aws.String(cloudformation.StackStatusUpdateComplete),
+				EnableTerminationProtection: aws.Bool(false),
+			}, nil
+		},
+		setProtectionStub: func(stackName *string, enabled bool) error {
+			if enabled {
+				protectedStack = stackName
+			}
+			return nil
+		},
+	}
+	ucmd := &ensureCmd{
+		cm: &CommandManagement{
+			cfnManager: mockCfnManager,
+			config:     &config{mode: noninteractive},
+		},
+		target:  "prod",
+		protect: true,
+	}
+
+	// act
+	err := ucmd.runE(nil, nil)
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aws.StringValue(protectedStack) != "arn:aws:cloudformation:us-east-1:111111111111:stack/prod/1" {
+		t.Errorf("Stack should be protected once ensured. %v", aws.StringValue(protectedStack))
+	}
+}
+
+func TestEnsureCmdRunE_ProtectOnlyWhenExecuted(t *testing.T) {
+
+	// arrange
+	protected := false
+	mockCfnManager := &mockCfnManager{
+		getStackStub: func(stackName *string) (*cloudformation.Stack, error) {
+			return &cloudformation.Stack{
+				StackId:                     aws.String("arn:aws:cloudformation:us-east-1:111111111111:stack/prod/1"),
+				StackStatus:                 aws.String(cloudformation.StackStatusUpdateComplete),
+				EnableTerminationProtection: aws.Bool(false),
+			}, nil
+		},
+		setProtectionStub: func(stackName *string, enabled bool) error {
+			protected = true
+			return nil
+		},
+	}
+	ucmd := &ensureCmd{
+		cm: &CommandManagement{
+			cfnManager: mockCfnManager,
+			config:     &config{mode: dry},
+		},
+		target:  "prod",
+		protect: true,
+	}
+
+	// act
+	err := ucmd.runE(nil, nil)
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if protected {
+		t.Error("Stack should not be protected when the change set was not executed.")
+	}
+}
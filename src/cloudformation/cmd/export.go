@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// noEchoPlaceholder is written instead of NoEcho parameter values, which cannot be read back.
+const noEchoPlaceholder = "REPLACE_ME_NOECHO"
+
+// ensureConfig is the config file format accepted by the ensure command. See test-assets/ensure.yml.
+type ensureConfig struct {
+	Target       string            `yaml:"target"`
+	TemplatePath string            `yaml:"template-path"`
+	Param        map[string]string `yaml:"param,omitempty"`
+	Mode         string            `yaml:"mode"`
+	Tag          map[string]string `yaml:"tag,omitempty"`
+}
+
+type exportCmd struct {
+	target  string
+	outPath string
+	cm      *CommandManagement
+	cmd     *cobra.Command
+}
+
+func (uc *exportCmd) runE(cmd *cobra.Command, args []string) error {
+
+	cfnManager := uc.cm.cfnManager
+	ctx := uc.cm.context()
+
+	stack, stackErr := cfnManager.getStack(ctx, &uc.target)
+	if stackErr != nil {
+		return stackErr
+	}
+	if stack == nil {
+		return errors.New(fmt.Sprintf("Stack %v not found.", uc.target))
+	}
+	stackTemplate, stackTemplateErr := cfnManager.getStackTemplate(ctx, &uc.target)
+	if stackTemplateErr != nil {
+		return stackTemplateErr
+	}
+
+	// The config points at the template with an absolute path, so it works from any directory.
+	outPath, absErr := filepath.Abs(uc.outPath)
+	if absErr != nil {
+		return absErr
+	}
+	stackName := aws.StringValue(stack.StackName)
+	templatePath := filepath.Join(outPath, stackName+".template")
+	configPath := filepath.Join(outPath, stackName+".yml")
+	config, noEchoParams := exportConfig(stack, templatePath)
+	configContent, configErr := yaml.Marshal(config)
+	if configErr != nil {
+		return configErr
+	}
+
+	if len(noEchoParams) > 0 {
+		fmt.Printf("NoEcho parameters cannot be exported. Please replace %v in %v for: %v\n", noEchoPlaceholder, configPath, strings.Join(noEchoParams, ", "))
+	}
+
+	if uc.cm.config.mode == dry {
+		fmt.Printf("This is a dry run. Would write %v and %v:\n%v", templatePath, configPath, string(configContent))
+		return nil
+	}
+
+	if mkdirErr := os.MkdirAll(outPath, 0755); mkdirErr != nil {
+		return mkdirErr
+	}
+	fmt.Printf("Writing template to: %v\n", templatePath)
+	if writeErr := ioutil.WriteFile(templatePath, []byte(*stackTemplate), 0644); writeErr != nil {
+		return writeErr
+	}
+	fmt.Printf("Writing config to: %v\n", configPath)
+	return ioutil.WriteFile(configPath, configContent, 0644)
+}
+
+// exportConfig builds the ensure config of a stack. Returns the keys of NoEcho parameters left as placeholders.
+func exportConfig(stack *cloudformation.Stack, templatePath string) (*ensureConfig, []string) {
+	config := &ensureConfig{
+		Target:       aws.StringValue(stack.StackName),
+		TemplatePath: templatePath,
+		Param:        make(map[string]string),
+		Mode:         interactive.String(), // an exported config never runs unattended until edited
+		Tag:          make(map[string]string),
+	}
+
+	noEchoParams := make([]string, 0)
+	for _, param := range stack.Parameters {
+		key := aws.StringValue(param.ParameterKey)
+		value := aws.StringValue(param.ParameterValue)
+		if value == noEchoMask {
+			value = noEchoPlaceholder
+			noEchoParams = append(noEchoParams, key)
+		}
+		config.Param[key] = value
+	}
+	for _, tag := range stack.Tags {
+		config.Tag[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+
+	return config, noEchoParams
+}
+
+func (uc *exportCmd) preRunE(cmd *cobra.Command, args []string) error {
+
+	localViper := uc.cm.viper
+	uc.target = localViper.GetString("target")
+	uc.outPath = localViper.GetString("out")
+
+	// parameter validations
+	var errstrings []string
+	if uc.target == "" {
+		errstrings = append(errstrings, "Please specify target stack to export.")
+	}
+	if uc.outPath == "" {
+		errstrings = append(errstrings, "Please specify directory to export to.")
+	}
+
+	if len(errstrings) > 0 {
+		return errors.New(strings.Join(errstrings, "\n"))
+	}
+
+	return nil
+}
+
+var exportCmdLong = `Export the template of a live stack together with an ensure config file holding its current parameters and tags.`
+
+func (cm *CommandManagement) initExportCmd() {
+
+	// init command structure
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "export",
+		Long:  exportCmdLong,
+	}
+	ucmd := &exportCmd{
+		cm:  cm,
+		cmd: cmd,
+	}
+
+	// local params
+	cmd.Flags().StringP("target", "t", "", "Stack name or arn to export")
+	cmd.Flags().StringP("out", "o", "", "Directory to write the template and config file to")
+
+	// wire methods.
+	cmd.PreRunE = ucmd.preRunE
+	cmd.RunE = ucmd.runE
+
+	// register
+	cm.root.AddCommand(cmd)
+}
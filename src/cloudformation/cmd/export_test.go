@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+func TestExportCmdPreRunE_ValidatesParams(t *testing.T) {
+	ucmd := &exportCmd{
+		cm: &CommandManagement{
+			viper: viper.New(),
+		},
+	}
+
+	err := ucmd.preRunE(nil, nil)
+
+	if err == nil {
+		t.Error("Command export parameters validation failed.")
+	}
+}
+
+func TestExportCmdRunE_WritesTemplateAndConfig(t *testing.T) {
+	// arrange
+	dir, _ := ioutil.TempDir("", "export")
+	defer os.RemoveAll(dir)
+	mockCfnManager := &mockCfnManager{
+		getStackStub: func(stackName *string) (*cloudformation.Stack, error) {
+			return &cloudformation.Stack{
+				StackName: aws.String("test-stack"),
+				Parameters: []*cloudformation.Parameter{
+					&cloudformation.Parameter{ParameterKey: aws.String("TestPath"), ParameterValue: aws.String("testasdf")},
+					&cloudformation.Parameter{ParameterKey: aws.String("Secret"), ParameterValue: aws.String(noEchoMask)},
+				},
+				Tags: []*cloudformation.Tag{
+					&cloudformation.Tag{Key: aws.String("Tag1"), Value: aws.String("asdfasdf")},
+				},
+			}, nil
+		},
+		getStackTemplateStub: func(stackName *string) (*string, error) {
+			return aws.String("Resources: {}"), nil
+		},
+	}
+	ucmd := &exportCmd{
+		cm: &CommandManagement{
+			cfnManager: mockCfnManager,
+			config:     &config{mode: noninteractive},
+		},
+		target:  "test-stack",
+		outPath: dir,
+	}
+
+	// act
+	err := ucmd.runE(nil, nil)
+
+	// assert
+	if err != nil {
+		t.Fatalf("Command export should not fail. %v", err)
+	}
+	template, _ := ioutil.ReadFile(filepath.Join(dir, "test-stack.template"))
+	if string(template) != "Resources: {}" {
+		t.Error("Template should be exported as is.")
+	}
+	content, _ := ioutil.ReadFile(filepath.Join(dir, "test-stack.yml"))
+	config := &ensureConfig{}
+	yaml.Unmarshal(content, config)
+	if config.Target != "test-stack" || config.TemplatePath != filepath.Join(dir, "test-stack.template") {
+		t.Errorf("Config should point at the exported template. %#v", config)
+	}
+	if config.Param["TestPath"] != "testasdf" || config.Param["Secret"] != noEchoPlaceholder {
+		t.Errorf("Parameters exported incorrectly. %#v", config.Param)
+	}
+	if config.Tag["Tag1"] != "asdfasdf" {
+		t.Errorf("Tags exported incorrectly. %#v", config.Tag)
+	}
+	if config.Mode != "interactive" {
+		t.Errorf("Config should default to the interactive mode. %#v", config.Mode)
+	}
+}
+
+func TestExportCmdRunE_RelativeOut(t *testing.T) {
+	// arrange
+	dir, _ := ioutil.TempDir("", "export")
+	defer os.RemoveAll(dir)
+	cwd, _ := os.Getwd()
+	relativeDir, _ := filepath.Rel(cwd, dir)
+	ucmd := &exportCmd{
+		cm: &CommandManagement{
+			cfnManager: &mockCfnManager{
+				getStackStub: func(stackName *string) (*cloudformation.Stack, error) {
+					return &cloudformation.Stack{StackName: aws.String("test-stack")}, nil
+				},
+			},
+			config: &config{mode: noninteractive},
+		},
+		target:  "test-stack",
+		outPath: relativeDir,
+	}
+
+	// act
+	err := ucmd.runE(nil, nil)
+
+	// assert
+	if err != nil {
+		t.Fatalf("Command export should not fail. %v", err)
+	}
+	content, _ := ioutil.ReadFile(filepath.Join(dir, "test-stack.yml"))
+	config := &ensureConfig{}
+	yaml.Unmarshal(content, config)
+	if config.TemplatePath != filepath.Join(dir, "test-stack.template") {
+		t.Errorf("Config should point at the template independently of the current directory. %v", config.TemplatePath)
+	}
+}
@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// importedResource is an entry of the resources file: the existing resource a logical id of the template adopts.
+type importedResource struct {
+	Type       string            `yaml:"type"`
+	Identifier map[string]string `yaml:"identifier"` // e.g. BucketName: my-bucket
+}
+
+type importCmd struct {
+	target         string
+	params         map[string]string
+	tags           map[string]string
+	templatePath   string
+	templateBucket string
+	resources      map[string]*importedResource // logical id to the resource it adopts
+	capabilities   []*string
+	cm             *CommandManagement
+	cmd            *cobra.Command
+}
+
+func (uc *importCmd) runE(cmd *cobra.Command, args []string) error {
+
+	cfnManager := uc.cm.cfnManager
+	ctx := uc.cm.context()
+
+	// Resources can be imported into a new stack as well as an existing one.
+	stack, stackErr := cfnManager.getStack(ctx, &uc.target)
+	if stackErr != nil {
+		return stackErr
+	}
+
+	// The imported resources have to be declared and retained by the template.
+	templateBody, readErr := ioutil.ReadFile(uc.templatePath)
+	if readErr != nil {
+		return readErr
+	}
+	if checkErr := checkImportTemplate(templateBody, uc.resources); checkErr != nil {
+		return checkErr
+	}
+
+	template, templateErr := uc.cm.loadTemplate(ctx, &templateSource{
+		path:   uc.templatePath,
+		bucket: uc.templateBucket,
+	}, &uc.target, stack != nil)
+	if templateErr != nil {
+		return templateErr
+	}
+
+	// Parameters
+	stackParams, spErr := uc.cm.filterParameters(ctx, template, &uc.params, stack != nil)
+	if spErr != nil {
+		return spErr
+	}
+
+	// Override tags
+	oldTags := make([]*cloudformation.Tag, 0)
+	if stack != nil {
+		oldTags = stack.Tags
+	}
+	stackTags := uc.cm.mergeTags(oldTags, &uc.tags)
+
+	options := &changeSetOptions{
+		capabilities:      uc.capabilities,
+		resourcesToImport: resourcesToImport(uc.resources),
+	}
+	_, executeErr := uc.cm.createAndExecute(ctx, &uc.target, stackParams, stackTags, template, cloudformation.ChangeSetTypeImport, options)
+	return executeErr
+}
+
+// readImportedResources reads and validates a resources file, a yaml map of logical ids to the resources they adopt.
+func readImportedResources(resourcesFile string) (map[string]*importedResource, error) {
+	content, readErr := ioutil.ReadFile(resourcesFile)
+	if readErr != nil {
+		return nil, readErr
+	}
+	resources := make(map[string]*importedResource)
+	if parseErr := yaml.Unmarshal(content, &resources); parseErr != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to parse resources file %v: %v", resourcesFile, parseErr))
+	}
+	if len(resources) == 0 {
+		return nil, errors.New(fmt.Sprintf("No resources in resources file %v.", resourcesFile))
+	}
+
+	for logicalId, resource := range resources {
+		if resource == nil || resource.Type == "" {
+			return nil, errors.New(fmt.Sprintf("Resource %v: please specify its type.", logicalId))
+		}
+		if len(resource.Identifier) == 0 {
+			return nil, errors.New(fmt.Sprintf("Resource %v: please specify its identifier.", logicalId))
+		}
+	}
+	return resources, nil
+}
+
+// checkImportTemplate checks that the template declares every imported resource with its type and DeletionPolicy: Retain.
+// CloudFormation refuses imports of resources that are not retained.
+func checkImportTemplate(templateBody []byte, resources map[string]*importedResource) error {
+	var document yaml.Node
+	if parseErr := yaml.Unmarshal(templateBody, &document); parseErr != nil {
+		return parseErr
+	}
+	if len(document.Content) == 0 {
+		return errors.New("Template is empty.")
+	}
+	templateResources := mappingValue(document.Content[0], "Resources")
+
+	problems := make([]string, 0)
+	for _, logicalId := range sortedLogicalIds(resources) {
+		declared := mappingValue(templateResources, logicalId)
+		if declared == nil {
+			problems = append(problems, fmt.Sprintf("%v is not declared in the template.", logicalId))
+			continue
+		}
+		if resourceType := mappingValue(declared, "Type"); resourceType == nil || resourceType.Value != resources[logicalId].Type {
+			problems = append(problems, fmt.Sprintf("%v is not of type %v in the template.", logicalId, resources[logicalId].Type))
+		}
+		if deletionPolicy := mappingValue(declared, "DeletionPolicy"); deletionPolicy == nil || deletionPolicy.Value != "Retain" {
+			problems = append(problems, fmt.Sprintf("%v needs DeletionPolicy: Retain in the template.", logicalId))
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New(fmt.Sprintf("The template cannot import the resources:\n%v", strings.Join(problems, "\n")))
+	}
+	return nil
+}
+
+// resourcesToImport returns the resources of the change set, ordered by logical id.
+func resourcesToImport(resources map[string]*importedResource) []*cloudformation.ResourceToImport {
+	result := make([]*cloudformation.ResourceToImport, 0, len(resources))
+	for _, logicalId := range sortedLogicalIds(resources) {
+		result = append(result, &cloudformation.ResourceToImport{
+			LogicalResourceId:  aws.String(logicalId),
+			ResourceType:       aws.String(resources[logicalId].Type),
+			ResourceIdentifier: aws.StringMap(resources[logicalId].Identifier),
+		})
+	}
+	return result
+}
+
+func sortedLogicalIds(resources map[string]*importedResource) []string {
+	logicalIds := make([]string, 0, len(resources))
+	for logicalId := range resources {
+		logicalIds = append(logicalIds, logicalId)
+	}
+	sort.Strings(logicalIds)
+	return logicalIds
+}
+
+func (uc *importCmd) preRunE(cmd *cobra.Command, args []string) error {
+
+	localViper := uc.cm.viper
+	uc.target = localViper.GetString("target")
+	uc.params = localViper.GetStringMapString("param")
+	uc.tags = localViper.GetStringMapString("tag")
+	uc.templatePath = localViper.GetString("template-path")
+	uc.templateBucket = localViper.GetString("template-bucket")
+	resourcesFile := localViper.GetString("resources")
+	capabilities, errstrings := readCapabilities(localViper)
+	uc.capabilities = capabilities
+
+	// parameter validations
+	if uc.target == "" {
+		errstrings = append(errstrings, "Please specify target stack to import into.")
+	}
+	if uc.templatePath == "" {
+		errstrings = append(errstrings, "Please specify template-path declaring the resources to import.")
+	}
+	if resourcesFile == "" {
+		errstrings = append(errstrings, "Please specify the resources file.")
+	} else if resources, resourcesErr := readImportedResources(resourcesFile); resourcesErr != nil {
+		errstrings = append(errstrings, resourcesErr.Error())
+	} else {
+		uc.resources = resources
+	}
+
+	if len(errstrings) > 0 {
+		return errors.New(strings.Join(errstrings, "\n"))
+	}
+
+	return nil
+}
+
+var importCmdLong = `Bring existing resources under a cloudformation stack, new or existing, through an import change set.
+The template has to declare every imported resource with DeletionPolicy: Retain, and no other change.`
+
+func (cm *CommandManagement) initImportCmd() {
+
+	// init command structure
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "import",
+		Long:  importCmdLong,
+	}
+	ucmd := &importCmd{
+		cm:  cm,
+		cmd: cmd,
+	}
+
+	// local params
+	cmd.Flags().StringP("target", "t", "", "Stack name or arn to import into")
+	cmd.Flags().StringToStringP("param", "p", nil, "Parameters to override")
+	cmd.Flags().StringToStringP("tag", "g", nil, "Tags to override")
+	cmd.Flags().String("template-path", "", "Template declaring the stack with the resources to import")
+	cmd.Flags().String("template-bucket", "", "S3 bucket to stage the template in. Required for templates over 51200 bytes")
+	cmd.Flags().String("resources", "", "Yaml file mapping the logical ids of the resources to import to their type and identifier")
+	addCapabilitiesFlag(cmd)
+
+	// wire methods.
+	cmd.PreRunE = ucmd.preRunE
+	cmd.RunE = ucmd.runE
+
+	// register
+	cm.root.AddCommand(cmd)
+}
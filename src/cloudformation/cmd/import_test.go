@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/spf13/viper"
+)
+
+const importTemplate = `Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+    DeletionPolicy: Retain
+  Table:
+    Type: AWS::DynamoDB::Table
+    Properties:
+      TableName: !Ref AWS::StackName
+`
+
+func TestCheckImportTemplate(t *testing.T) {
+	// arrange
+	resources := map[string]*importedResource{
+		"Bucket": {Type: "AWS::S3::Bucket", Identifier: map[string]string{"BucketName": "my-bucket"}},
+		"Table":  {Type: "AWS::DynamoDB::Table", Identifier: map[string]string{"TableName": "my-table"}},
+		"Queue":  {Type: "AWS::SQS::Queue", Identifier: map[string]string{"QueueUrl": "https://queue"}},
+	}
+
+	// act
+	err := checkImportTemplate([]byte(importTemplate), resources)
+	retainedErr := checkImportTemplate([]byte(importTemplate), map[string]*importedResource{"Bucket": resources["Bucket"]})
+
+	// assert
+	if err == nil || !strings.Contains(err.Error(), "Table needs DeletionPolicy: Retain") || !strings.Contains(err.Error(), "Queue is not declared") {
+		t.Errorf("Unretained and undeclared resources should be refused. %v", err)
+	}
+	if strings.Contains(err.Error(), "Bucket") {
+		t.Errorf("Retained resource should pass. %v", err)
+	}
+	if retainedErr != nil {
+		t.Errorf("Retained resource should pass. %v", retainedErr)
+	}
+}
+
+func TestReadImportedResources_Invalid(t *testing.T) {
+	// arrange
+	dir, _ := ioutil.TempDir("", "import")
+	defer os.RemoveAll(dir)
+	resourcesPath := filepath.Join(dir, "resources.yml")
+	ioutil.WriteFile(resourcesPath, []byte("Bucket:\n  type: AWS::S3::Bucket\n"), 0600)
+
+	// act
+	_, err := readImportedResources(resourcesPath)
+
+	// assert
+	if err == nil || !strings.Contains(err.Error(), "identifier") {
+		t.Errorf("Resources without identifier should be refused. %v", err)
+	}
+}
+
+func TestImportCmdRunE_ImportChangeSet(t *testing.T) {
+	// arrange
+	dir, _ := ioutil.TempDir("", "import")
+	defer os.RemoveAll(dir)
+	templatePath := filepath.Join(dir, "template.yml")
+	ioutil.WriteFile(templatePath, []byte(importTemplate), 0600)
+	resourcesPath := filepath.Join(dir, "resources.yml")
+	ioutil.WriteFile(resourcesPath, []byte("Bucket:\n  type: AWS::S3::Bucket\n  identifier:\n    BucketName: my-bucket\n"), 0600)
+
+	csType := ""
+	mockCfnManager := &mockCfnManager{
+		getStackStub: func(stackName *string) (*cloudformation.Stack, error) {
+			return nil, nil
+		},
+		createChangeSetStub: func(stackName *string, params []*cloudformation.Parameter, tags []*cloudformation.Tag, template *cfnTemplate, changeSetType string) (*cloudformation.CreateChangeSetOutput, error) {
+			csType = changeSetType
+			return &cloudformation.CreateChangeSetOutput{Id: aws.String("cs"), StackId: aws.String("stack")}, nil
+		},
+	}
+	localViper := viper.New()
+	localViper.Set("target", "adopted")
+	localViper.Set("template-path", templatePath)
+	localViper.Set("resources", resourcesPath)
+	ucmd := &importCmd{
+		cm: &CommandManagement{
+			cfnManager: mockCfnManager,
+			config:     &config{mode: noninteractive},
+			viper:      localViper,
+		},
+	}
+
+	// act
+	preErr := ucmd.preRunE(nil, nil)
+	err := ucmd.runE(nil, nil)
+
+	// assert
+	if preErr != nil || err != nil {
+		t.Fatalf("Command import failed. %v %v", preErr, err)
+	}
+	if csType != cloudformation.ChangeSetTypeImport {
+		t.Errorf("Change set should be an import. %v", csType)
+	}
+	imported := mockCfnManager.changeSetOptions.resourcesToImport
+	if len(imported) != 1 || aws.StringValue(imported[0].LogicalResourceId) != "Bucket" || aws.StringValue(imported[0].ResourceIdentifier["BucketName"]) != "my-bucket" {
+		t.Errorf("Resources to import incorrect. %v", imported)
+	}
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+type listCmd struct {
+	filter *stackFilter
+	out    io.Writer // the list is printed here, os.Stdout when nil
+	cm     *CommandManagement
+	cmd    *cobra.Command
+}
+
+func (uc *listCmd) runE(cmd *cobra.Command, args []string) error {
+
+	ctx := uc.cm.context()
+
+	// Regions that cannot be listed are reported at the end.
+	allStacks, listErr := uc.cm.collectStacks(ctx)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	filter := uc.filter
+	if filter == nil {
+		filter = &stackFilter{}
+	}
+	stacks := make([]*cloudformation.Stack, 0, len(allStacks))
+	for _, stack := range allStacks {
+		if filter.match(stack) == "" {
+			stacks = append(stacks, stack)
+		}
+	}
+
+	out := uc.out
+	if out == nil {
+		out = os.Stdout
+	}
+	printStackList(out, stacks)
+	fmt.Fprintf(out, "%v of %v stack(s) listed.\n", len(stacks), len(allStacks))
+
+	return listErr
+}
+
+// printStackList renders the account, region, name, status and last update of stacks, ordered by account, region and name.
+func printStackList(out io.Writer, stacks []*cloudformation.Stack) {
+	type listedStack struct {
+		location stackLocation
+		stack    *cloudformation.Stack
+	}
+	listed := make([]*listedStack, 0, len(stacks))
+	for _, stack := range stacks {
+		listed = append(listed, &listedStack{location: locateStack(stack.StackId), stack: stack})
+	}
+	sort.SliceStable(listed, func(i, j int) bool {
+		if listed[i].location != listed[j].location {
+			return listed[i].location.String() < listed[j].location.String()
+		}
+		return aws.StringValue(listed[i].stack.StackName) < aws.StringValue(listed[j].stack.StackName)
+	})
+
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ACCOUNT\tREGION\tSTACK\tSTATUS\tLAST UPDATED")
+	for _, entry := range listed {
+		updated := entry.stack.LastUpdatedTime
+		if updated == nil {
+			updated = entry.stack.CreationTime
+		}
+		lastUpdated := ""
+		if updated != nil {
+			lastUpdated = updated.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\n",
+			orDash(entry.location.accountId),
+			orDash(entry.location.region),
+			aws.StringValue(entry.stack.StackName),
+			aws.StringValue(entry.stack.StackStatus),
+			orDash(lastUpdated))
+	}
+	tw.Flush()
+}
+
+func (uc *listCmd) preRunE(cmd *cobra.Command, args []string) error {
+
+	filter, errstrings := readStackFilter(uc.cm.viper)
+	if len(errstrings) > 0 {
+		return errors.New(strings.Join(errstrings, "\n"))
+	}
+	uc.filter = filter
+
+	return nil
+}
+
+var listCmdLong = `List the stacks in the selected regions and accounts, with their account, region, status and last update.
+The selector flags narrow down the stacks to list. Every selector given must match.`
+
+func (cm *CommandManagement) initListCmd() {
+
+	// init command structure
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "list",
+		Long:  listCmdLong,
+	}
+	ucmd := &listCmd{
+		cm:  cm,
+		cmd: cmd,
+	}
+
+	// local params
+	addStackFilterFlags(cmd)
+
+	// wire methods.
+	cmd.PreRunE = ucmd.preRunE
+	cmd.RunE = ucmd.runE
+
+	// register
+	cm.root.AddCommand(cmd)
+}
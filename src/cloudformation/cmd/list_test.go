@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+)
+
+func TestPrintStackList(t *testing.T) {
+	// arrange
+	stacks := []*cloudformation.Stack{
+		&cloudformation.Stack{
+			StackName:   aws.String("b"),
+			StackId:     aws.String("arn:aws:cloudformation:us-east-1:222222222222:stack/b/id"),
+			StackStatus: aws.String(cloudformation.StackStatusCreateComplete),
+		},
+		&cloudformation.Stack{
+			StackName:   aws.String("a"),
+			StackId:     aws.String("arn:aws:cloudformation:us-east-1:111111111111:stack/a/id"),
+			StackStatus: aws.String(cloudformation.StackStatusUpdateComplete),
+		},
+	}
+	out := &bytes.Buffer{}
+
+	// act
+	printStackList(out, stacks)
+
+	// assert
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected a header and a line per stack. %v", out.String())
+	}
+	if !strings.HasPrefix(lines[1], "111111111111  us-east-1  a") || !strings.HasPrefix(lines[2], "222222222222  us-east-1  b") {
+		t.Errorf("Stacks should be listed with their account, by account. %v", out.String())
+	}
+}
+
+func TestListCmdRunE_Filter(t *testing.T) {
+	// arrange
+	mockCfnManager := &mockCfnManager{
+		regionCount: 1,
+		getAllStub: func(stackChannel chan *cloudformation.Stack, errChannel chan error) {
+			stackChannel <- testStack("sandbox-api")
+			stackChannel <- testStack("prod-api")
+		},
+	}
+	out := &bytes.Buffer{}
+	ucmd := &listCmd{
+		cm: &CommandManagement{
+			cfnManager: mockCfnManager,
+			config:     &config{mode: noninteractive},
+		},
+		filter: &stackFilter{names: []string{"sandbox-*"}},
+		out:    out,
+	}
+
+	// act
+	err := ucmd.runE(nil, nil)
+
+	// assert
+	if err != nil {
+		t.Error(err)
+	}
+	if !strings.Contains(out.String(), "sandbox-api") || strings.Contains(out.String(), "prod-api") {
+		t.Errorf("Only the selected stacks should be listed. %v", out.String())
+	}
+	if !strings.Contains(out.String(), "1 of 2 stack(s) listed.") {
+		t.Errorf("The count of listed stacks should be printed. %v", out.String())
+	}
+}
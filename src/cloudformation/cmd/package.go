@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"strings"
+)
+
+type packageCmd struct {
+	templatePath   string
+	artifactBucket string
+	outPath        string
+	cm             *CommandManagement
+	cmd            *cobra.Command
+}
+
+func (uc *packageCmd) runE(cmd *cobra.Command, args []string) error {
+
+	packager := &templatePackager{
+		ctx:             uc.cm.context(),
+		artifactManager: uc.cm.artifactManager,
+		bucket:          uc.artifactBucket,
+	}
+	packaged, packageErr := packager.packageTemplate(uc.templatePath)
+	if packageErr != nil {
+		return packageErr
+	}
+
+	if uc.outPath == "" {
+		fmt.Print(packaged)
+		return nil
+	}
+
+	fmt.Printf("Writing packaged template to: %v\n", uc.outPath)
+	return ioutil.WriteFile(uc.outPath, []byte(packaged), 0644)
+}
+
+func (uc *packageCmd) preRunE(cmd *cobra.Command, args []string) error {
+
+	localViper := uc.cm.viper
+	uc.templatePath = localViper.GetString("template-path")
+	uc.artifactBucket = localViper.GetString("artifact-bucket")
+	uc.outPath = localViper.GetString("out")
+
+	// parameter validations
+	var errstrings []string
+	if uc.templatePath == "" {
+		errstrings = append(errstrings, "Please specify template to package.")
+	}
+	if uc.artifactBucket == "" {
+		errstrings = append(errstrings, "Please specify bucket to upload artifacts to.")
+	}
+
+	if len(errstrings) > 0 {
+		return errors.New(strings.Join(errstrings, "\n"))
+	}
+
+	return nil
+}
+
+var packageCmdLong = `Upload local artifacts referenced by a template (lambda code, nested stack templates, AWS::Include snippets, etc.) to s3 and rewrite the template to point at them.`
+
+func (cm *CommandManagement) initPackageCmd() {
+
+	// init command structure
+	cmd := &cobra.Command{
+		Use:   "package",
+		Short: "package",
+		Long:  packageCmdLong,
+	}
+	ucmd := &packageCmd{
+		cm:  cm,
+		cmd: cmd,
+	}
+
+	// local params
+	cmd.Flags().String("template-path", "", "Template to package")
+	cmd.Flags().String("artifact-bucket", "", "S3 bucket to upload local artifacts to")
+	cmd.Flags().StringP("out", "o", "", "File to write the packaged template to. Defaults to stdout")
+
+	// wire methods.
+	cmd.PreRunE = ucmd.preRunE
+	cmd.RunE = ucmd.runE
+
+	// register
+	cm.root.AddCommand(cmd)
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestPackageCmdPreRunE_ValidatesParams(t *testing.T) {
+	ucmd := &packageCmd{
+		cm: &CommandManagement{
+			viper: viper.New(),
+		},
+	}
+
+	err := ucmd.preRunE(nil, nil)
+
+	if err == nil {
+		t.Error("Command package parameters validation failed.")
+	}
+}
+
+func TestPackageCmdPreRunE_Success(t *testing.T) {
+	vip := viper.New()
+	vip.Set("template-path", "template.yml")
+	vip.Set("artifact-bucket", "bucket")
+	ucmd := &packageCmd{
+		cm: &CommandManagement{
+			viper: vip,
+		},
+	}
+
+	err := ucmd.preRunE(nil, nil)
+
+	if err != nil {
+		t.Error("Command package parameters validation failed.")
+	}
+}
@@ -0,0 +1,262 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// allowAllPolicy is the policy a stack policy is cleared with. CloudFormation cannot remove the policy of a stack.
+const allowAllPolicy = `{"Statement":[{"Effect":"Allow","Action":"Update:*","Principal":"*","Resource":"*"}]}`
+
+type policyCmd struct {
+	target     string
+	policyPath string
+	policy     *string // read from policyPath
+	cm         *CommandManagement
+	cmd        *cobra.Command
+}
+
+func (uc *policyCmd) runE(cmd *cobra.Command, args []string) error {
+
+	switch cmd.Name() {
+	case "set":
+		return uc.replacePolicy(uc.policy)
+	case "clear":
+		fmt.Println("CloudFormation cannot remove a stack policy. It is replaced with one allowing every update.")
+		return uc.replacePolicy(aws.String(allowAllPolicy))
+	}
+
+	policy, policyErr := uc.cm.cfnManager.getStackPolicy(uc.cm.context(), &uc.target)
+	if policyErr != nil {
+		return policyErr
+	}
+	if policy == nil {
+		fmt.Printf("Stack %v has no policy.\n", uc.target)
+		return nil
+	}
+	fmt.Println(formatPolicy(policy))
+	return nil
+}
+
+// replacePolicy shows the current and new policy of the target, then replaces it.
+func (uc *policyCmd) replacePolicy(policy *string) error {
+
+	cfnManager := uc.cm.cfnManager
+	ctx := uc.cm.context()
+
+	current, currentErr := cfnManager.getStackPolicy(ctx, &uc.target)
+	if currentErr != nil {
+		return currentErr
+	}
+	if current == nil {
+		fmt.Printf("Stack %v has no policy.\n", uc.target)
+	} else {
+		fmt.Printf("Current policy of %v:\n%v\n", uc.target, formatPolicy(current))
+	}
+	fmt.Printf("New policy:\n%v\n", formatPolicy(policy))
+
+	if uc.cm.config.mode == dry {
+		fmt.Println("This is a dry run. The stack policy was not changed.")
+		return nil
+	}
+	if uc.cm.config.mode == interactive && !uc.cm.confirm(ctx) {
+		return nil
+	}
+
+	if setErr := cfnManager.setStackPolicy(ctx, &uc.target, policy); setErr != nil {
+		return setErr
+	}
+	fmt.Printf("Stack policy of %v updated.\n", uc.target)
+	return nil
+}
+
+// readStackPolicy reads a stack policy from a json or yaml file and returns it as json.
+func readStackPolicy(policyPath string) (*string, error) {
+	content, readErr := ioutil.ReadFile(policyPath)
+	if readErr != nil {
+		return nil, readErr
+	}
+	var document interface{}
+	if parseErr := yaml.Unmarshal(content, &document); parseErr != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to parse stack policy %v: %v", policyPath, parseErr))
+	}
+	if policy, isMap := document.(map[string]interface{}); !isMap || policy["Statement"] == nil {
+		return nil, errors.New(fmt.Sprintf("Stack policy %v has no Statement.", policyPath))
+	}
+	body, marshalErr := json.Marshal(document)
+	if marshalErr != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to convert stack policy %v to json: %v", policyPath, marshalErr))
+	}
+	return aws.String(string(body)), nil
+}
+
+// formatPolicy indents a json policy for display. Policies that are not valid json are returned as is.
+func formatPolicy(policy *string) string {
+	var out bytes.Buffer
+	if indentErr := json.Indent(&out, []byte(aws.StringValue(policy)), "", "  "); indentErr != nil {
+		return aws.StringValue(policy)
+	}
+	return out.String()
+}
+
+// checkPolicyDuringUpdate refuses a policy during update in the modes that don't execute the change set.
+// Change sets carry no StackPolicyDuringUpdateBody, so the policy is swapped in while the change set executes.
+func checkPolicyDuringUpdate(mode mode, policy *string) error {
+	if policy == nil {
+		return nil
+	}
+	var reason string
+	switch mode {
+	case dry:
+		reason = "a dry run never executes it"
+	case changesetonly:
+		reason = "the change set is left to be executed outside of this command"
+	default:
+		return nil
+	}
+	return errors.New(fmt.Sprintf("policy-during-update cannot be used in %v mode. Change sets carry no stack policy, so the policy is swapped in only while the change set executes, and %v.", mode, reason))
+}
+
+// overrideStackPolicy swaps the policy of a stack for the one to update it with.
+// The returned func restores the policy of the stack once the update is over.
+func (cm *CommandManagement) overrideStackPolicy(ctx context.Context, stackName *string, policy *string) (func() error, error) {
+	out := cm.output()
+	noRestore := func() error { return nil }
+	if policy == nil {
+		return noRestore, nil
+	}
+
+	current, currentErr := cm.cfnManager.getStackPolicy(ctx, stackName)
+	if currentErr != nil {
+		return nil, currentErr
+	}
+	if current == nil {
+		fmt.Fprintln(out, "The stack has no policy, every update is allowed. Ignoring policy-during-update.")
+		return noRestore, nil
+	}
+
+	// Keep a trace of the policy to restore, should the command die before restoring it.
+	backupPath := policyBackupPath(stackName)
+	fmt.Fprintf(out, "Current stack policy, restored after the update:\n%v\n", formatPolicy(current))
+	if writeErr := ioutil.WriteFile(backupPath, []byte(formatPolicy(current)), 0600); writeErr != nil {
+		fmt.Fprintf(out, "Unable to back up the stack policy: %v\n", writeErr)
+	} else {
+		fmt.Fprintf(out, "Stack policy backed up to %v. Should the update be interrupted, restore it with: policy set --target %v --policy-path %v\n",
+			backupPath, aws.StringValue(stackName), backupPath)
+	}
+
+	fmt.Fprintln(out, "Overriding the stack policy during the update...")
+	if setErr := cm.cfnManager.setStackPolicy(ctx, stackName, policy); setErr != nil {
+		return nil, setErr
+	}
+	return func() error {
+		// Restore even when the command ran out of time or was cancelled.
+		restoreCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		fmt.Fprintln(out, "Restoring the stack policy...")
+		if restoreErr := cm.cfnManager.setStackPolicy(restoreCtx, stackName, current); restoreErr != nil {
+			return errors.New(fmt.Sprintf("Unable to restore the policy of stack %v: %v\nPlease restore it with policy set --policy-path %v:\n%v", aws.StringValue(stackName), restoreErr, backupPath, formatPolicy(current)))
+		}
+		os.Remove(backupPath)
+		return nil
+	}, nil
+}
+
+// policyBackupPath returns the file the policy of a stack is backed up to while it is overridden.
+func policyBackupPath(stackName *string) string {
+	name := aws.StringValue(stackName)
+	if parsed, arnErr := parseArn(stackName); arnErr == nil {
+		// stack/name/id
+		if parts := strings.Split(parsed.Resource, "/"); len(parts) > 1 {
+			name = parts[1]
+		}
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("machete-policy-%v-%v.json", name, time.Now().Unix()))
+}
+
+func (uc *policyCmd) preRunE(cmd *cobra.Command, args []string) error {
+
+	localViper := uc.cm.viper
+	uc.target = localViper.GetString("target")
+	uc.policyPath = localViper.GetString("policy-path")
+
+	// parameter validations
+	var errstrings []string
+	if uc.target == "" {
+		errstrings = append(errstrings, "Please specify target stack.")
+	}
+	if cmd.Name() == "set" {
+		if uc.policyPath == "" {
+			errstrings = append(errstrings, "Please specify policy-path.")
+		} else if policy, policyErr := readStackPolicy(uc.policyPath); policyErr != nil {
+			errstrings = append(errstrings, policyErr.Error())
+		} else {
+			uc.policy = policy
+		}
+	}
+
+	if len(errstrings) > 0 {
+		return errors.New(strings.Join(errstrings, "\n"))
+	}
+
+	return nil
+}
+
+var policyCmdLong = `Get, set or clear the policy of a stack. Policies are read from json or yaml files.
+CloudFormation cannot remove a stack policy: clear replaces it with a policy allowing every update.`
+
+func (cm *CommandManagement) initPolicyCmd() {
+
+	// init command structure
+	cmd := &cobra.Command{
+		Use:   "policy",
+		Short: "policy",
+		Long:  policyCmdLong,
+	}
+
+	subCmds := []struct {
+		use   string
+		short string
+	}{
+		{"get", "Print the policy of a stack"},
+		{"set", "Replace the policy of a stack with the one of policy-path"},
+		{"clear", "Replace the policy of a stack with one allowing every update"},
+	}
+	for _, subCmd := range subCmds {
+		sub := &cobra.Command{
+			Use:   subCmd.use,
+			Short: subCmd.short,
+			Long:  policyCmdLong,
+		}
+		ucmd := &policyCmd{
+			cm:  cm,
+			cmd: sub,
+		}
+
+		// local params
+		sub.Flags().StringP("target", "t", "", "Name or arn of the stack")
+		if subCmd.use == "set" {
+			sub.Flags().String("policy-path", "", "Json or yaml file of the stack policy")
+		}
+
+		// wire methods.
+		sub.PreRunE = ucmd.preRunE
+		sub.RunE = ucmd.runE
+
+		cmd.AddCommand(sub)
+	}
+
+	// register
+	cm.root.AddCommand(cmd)
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+)
+
+func TestReadStackPolicy(t *testing.T) {
+	// arrange
+	dir, _ := ioutil.TempDir("", "policy")
+	defer os.RemoveAll(dir)
+	yamlPath := filepath.Join(dir, "policy.yml")
+	ioutil.WriteFile(yamlPath, []byte("Statement:\n  - Effect: Deny\n    Action: Update:Replace\n    Principal: '*'\n    Resource: LogicalResourceId/Database\n"), 0600)
+	emptyPath := filepath.Join(dir, "empty.json")
+	ioutil.WriteFile(emptyPath, []byte(`{"Version": "1"}`), 0600)
+
+	// act
+	policy, err := readStackPolicy(yamlPath)
+	_, emptyErr := readStackPolicy(emptyPath)
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aws.StringValue(policy) != `{"Statement":[{"Action":"Update:Replace","Effect":"Deny","Principal":"*","Resource":"LogicalResourceId/Database"}]}` {
+		t.Errorf("Policy not converted to json. %v", aws.StringValue(policy))
+	}
+	if emptyErr == nil {
+		t.Error("A policy without statements should be refused.")
+	}
+}
+
+func TestCreateAndExecute_PolicyDuringUpdateRefusedInDryMode(t *testing.T) {
+	// arrange
+	created := false
+	mockCfnManager := &mockCfnManager{
+		createChangeSetStub: func(stackName *string, params []*cloudformation.Parameter, tags []*cloudformation.Tag, template *cfnTemplate, changeSetType string) (*cloudformation.CreateChangeSetOutput, error) {
+			created = true
+			return &cloudformation.CreateChangeSetOutput{Id: aws.String("cs"), StackId: aws.String("stack")}, nil
+		},
+	}
+	cm := &CommandManagement{
+		cfnManager: mockCfnManager,
+		config:     &config{mode: dry},
+	}
+
+	// act
+	_, err := cm.createAndExecute(context.Background(), aws.String("stack"), nil, nil, &cfnTemplate{body: aws.String("")}, cloudformation.ChangeSetTypeUpdate,
+		&changeSetOptions{policyDuringUpdate: aws.String(allowAllPolicy)})
+
+	// assert
+	if err == nil || !strings.Contains(err.Error(), "dry") {
+		t.Errorf("policy-during-update should be refused in dry mode. %v", err)
+	}
+	if created {
+		t.Error("No change set should be created.")
+	}
+}
+
+func TestCreateAndExecute_PolicyDuringUpdateSwappedWhileExecuting(t *testing.T) {
+	// arrange
+	calls := make([]string, 0)
+	mockCfnManager := &mockCfnManager{
+		createChangeSetStub: func(stackName *string, params []*cloudformation.Parameter, tags []*cloudformation.Tag, template *cfnTemplate, changeSetType string) (*cloudformation.CreateChangeSetOutput, error) {
+			return &cloudformation.CreateChangeSetOutput{Id: aws.String("cs"), StackId: aws.String("stack")}, nil
+		},
+		getStackPolicyStub: func(stackName *string) (*string, error) {
+			return aws.String("original"), nil
+		},
+		setStackPolicyStub: func(stackName *string, policy *string) error {
+			calls = append(calls, "set "+aws.StringValue(policy))
+			return nil
+		},
+		executeChangeSetStub: func(stackname *string, csName *string) error {
+			calls = append(calls, "execute")
+			return nil
+		},
+	}
+	cm := &CommandManagement{
+		cfnManager: mockCfnManager,
+		config:     &config{mode: noninteractive},
+	}
+
+	// act
+	_, err := cm.createAndExecute(context.Background(), aws.String("stack"), nil, nil, &cfnTemplate{body: aws.String("")}, cloudformation.ChangeSetTypeUpdate,
+		&changeSetOptions{policyDuringUpdate: aws.String("override")})
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(calls, ", ") != "set override, execute, set original" {
+		t.Errorf("Policy should be overridden while the change set executes, then restored. %v", calls)
+	}
+}
+
+func TestPolicyBackupPath(t *testing.T) {
+	// act
+	backupPath := policyBackupPath(aws.String("arn:aws:cloudformation:us-east-1:111111111111:stack/prod-db/1234"))
+
+	// assert
+	if !strings.HasPrefix(filepath.Base(backupPath), "machete-policy-prod-db-") {
+		t.Errorf("Backup should be named after the stack. %v", backupPath)
+	}
+}
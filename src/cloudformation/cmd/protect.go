@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+)
+
+type protectCmd struct {
+	filter *stackFilter
+	enable bool // protect, or unprotect
+	cm     *CommandManagement
+	cmd    *cobra.Command
+}
+
+func (uc *protectCmd) runE(cmd *cobra.Command, args []string) error {
+
+	cfnManager := uc.cm.cfnManager
+	ctx := uc.cm.context()
+
+	// Regions that cannot be listed are reported at the end.
+	allStacks, listErr := uc.cm.collectStacks(ctx)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	// Stacks already in the requested state are left alone.
+	stacks := make([]*cloudformation.Stack, 0, len(allStacks))
+	for _, stack := range allStacks {
+		if uc.filter.match(stack) != "" || aws.BoolValue(stack.EnableTerminationProtection) == uc.enable {
+			continue
+		}
+		if aws.StringValue(stack.StackStatus) == cloudformation.StackStatusReviewInProgress {
+			continue
+		}
+		// Nested stacks take the protection of their root, and refuse their own.
+		if stack.RootId != nil {
+			continue
+		}
+		stacks = append(stacks, stack)
+	}
+
+	action := "Protecting"
+	if !uc.enable {
+		action = "Unprotecting"
+	}
+	printStackList(os.Stdout, stacks)
+	fmt.Printf("%v %v of %v stack(s).\n", action, len(stacks), len(allStacks))
+	if len(stacks) == 0 {
+		return listErr
+	}
+
+	if uc.cm.config.mode == dry {
+		fmt.Println("This is a dry run. Termination protection was not changed.")
+		return listErr
+	}
+	if uc.cm.config.mode == interactive && !uc.cm.confirm(ctx) {
+		return listErr
+	}
+
+	failed := make([]string, 0)
+	for _, stack := range stacks {
+		if setErr := cfnManager.setTerminationProtection(ctx, stack.StackId, uc.enable); setErr != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			fmt.Printf("Unable to change termination protection of %v (%v): %v\n", aws.StringValue(stack.StackName), locateStack(stack.StackId), setErr)
+			failed = append(failed, aws.StringValue(stack.StackName))
+			continue
+		}
+		fmt.Printf("%v: termination protection %v.\n", aws.StringValue(stack.StackName), protectionState(uc.enable))
+	}
+
+	if len(failed) > 0 {
+		return errors.New(fmt.Sprintf("Unable to change termination protection of %v stack(s): %v", len(failed), strings.Join(failed, ", ")))
+	}
+	return listErr
+}
+
+func protectionState(enabled bool) string {
+	if enabled {
+		return "enabled"
+	}
+	return "disabled"
+}
+
+func (uc *protectCmd) preRunE(cmd *cobra.Command, args []string) error {
+
+	if uc.cm.config.mode == changesetonly {
+		return errors.New("Mode changesetonly is not allowed for protect / unprotect cmds.")
+	}
+
+	filter, errstrings := readStackFilter(uc.cm.viper)
+	// Termination protection is what keeps stacks from delete-all, so it is only stripped from every stack on request.
+	if !uc.enable && len(errstrings) == 0 && !filter.selective() && !uc.cm.viper.GetBool("all") {
+		errstrings = append(errstrings, "Please specify a selector of the stacks to unprotect, or all to unprotect every stack.")
+	}
+	if len(errstrings) > 0 {
+		return errors.New(strings.Join(errstrings, "\n"))
+	}
+	uc.filter = filter
+
+	return nil
+}
+
+var protectCmdLong = `Enable termination protection on the stacks in the selected regions and accounts.
+The selector flags narrow down the stacks to protect. Every selector given must match.`
+
+var unprotectCmdLong = `Disable termination protection on the stacks in the selected regions and accounts.
+The selector flags narrow down the stacks to unprotect. Every selector given must match.
+Without a selector, all has to be given to unprotect every stack.`
+
+func (cm *CommandManagement) initProtectCmd() {
+
+	for _, enable := range []bool{true, false} {
+		// init command structure
+		cmd := &cobra.Command{
+			Use:   "protect",
+			Short: "protect",
+			Long:  protectCmdLong,
+		}
+		if !enable {
+			cmd.Use, cmd.Short, cmd.Long = "unprotect", "unprotect", unprotectCmdLong
+		}
+		ucmd := &protectCmd{
+			enable: enable,
+			cm:     cm,
+			cmd:    cmd,
+		}
+
+		// local params
+		addStackFilterFlags(cmd)
+		if !enable {
+			cmd.Flags().Bool("all", false, "Unprotect every stack when no selector is given")
+		}
+
+		// wire methods.
+		cmd.PreRunE = ucmd.preRunE
+		cmd.RunE = ucmd.runE
+
+		// register
+		cm.root.AddCommand(cmd)
+	}
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/spf13/viper"
+)
+
+func TestProtectCmdRunE_Filtered(t *testing.T) {
+	// arrange
+	protected := make(map[string]bool)
+	mockCfnManager := &mockCfnManager{
+		getAllStub: func(stackChan chan *cloudformation.Stack, errChan chan error) {
+			stackChan <- &cloudformation.Stack{
+				StackName:                   aws.String("prod-api"),
+				StackId:                     aws.String("arn:aws:cloudformation:us-east-1:111111111111:stack/prod-api/1"),
+				StackStatus:                 aws.String(cloudformation.StackStatusCreateComplete),
+				EnableTerminationProtection: aws.Bool(false),
+			}
+			stackChan <- &cloudformation.Stack{
+				StackName:                   aws.String("prod-db"),
+				StackId:                     aws.String("arn:aws:cloudformation:us-east-1:111111111111:stack/prod-db/2"),
+				StackStatus:                 aws.String(cloudformation.StackStatusCreateComplete),
+				EnableTerminationProtection: aws.Bool(true),
+			}
+			stackChan <- &cloudformation.Stack{
+				StackName:                   aws.String("prod-api-Nested"),
+				StackId:                     aws.String("arn:aws:cloudformation:us-east-1:111111111111:stack/prod-api-Nested/4"),
+				StackStatus:                 aws.String(cloudformation.StackStatusCreateComplete),
+				EnableTerminationProtection: aws.Bool(false),
+				RootId:                      aws.String("arn:aws:cloudformation:us-east-1:111111111111:stack/prod-api/1"),
+			}
+			stackChan <- &cloudformation.Stack{
+				StackName:                   aws.String("sandbox"),
+				StackId:                     aws.String("arn:aws:cloudformation:us-east-1:111111111111:stack/sandbox/3"),
+				StackStatus:                 aws.String(cloudformation.StackStatusCreateComplete),
+				EnableTerminationProtection: aws.Bool(false),
+			}
+		},
+		setProtectionStub: func(stackName *string, enabled bool) error {
+			protected[aws.StringValue(stackName)] = enabled
+			return nil
+		},
+		regionCount: 1,
+	}
+	localViper := viper.New()
+	localViper.Set("name", []string{"prod-*"})
+	ucmd := &protectCmd{
+		enable: true,
+		cm: &CommandManagement{
+			cfnManager: mockCfnManager,
+			config:     &config{mode: noninteractive},
+			viper:      localViper,
+		},
+	}
+
+	// act
+	preErr := ucmd.preRunE(nil, nil)
+	err := ucmd.runE(nil, nil)
+
+	// assert
+	if preErr != nil || err != nil {
+		t.Errorf("Command protect failed. %v %v", preErr, err)
+	}
+	if len(protected) != 1 || !protected["arn:aws:cloudformation:us-east-1:111111111111:stack/prod-api/1"] {
+		t.Errorf("Only the selected unprotected root stack should be protected. %v", protected)
+	}
+}
+
+func TestProtectCmdPreRunE_ChangeSetOnly(t *testing.T) {
+	// arrange
+	ucmd := &protectCmd{
+		cm: &CommandManagement{
+			config: &config{mode: changesetonly},
+			viper:  viper.New(),
+		},
+	}
+
+	// act
+	err := ucmd.preRunE(nil, nil)
+
+	// assert
+	if err == nil {
+		t.Error("Mode changesetonly should be refused.")
+	}
+}
+
+func TestProtectCmdPreRunE_UnprotectNeedsSelector(t *testing.T) {
+	// arrange
+	localViper := viper.New()
+	ucmd := &protectCmd{
+		cm: &CommandManagement{
+			config: &config{mode: noninteractive},
+			viper:  localViper,
+		},
+	}
+
+	// act
+	noSelectorErr := ucmd.preRunE(nil, nil)
+	localViper.Set("all", true)
+	allErr := ucmd.preRunE(nil, nil)
+
+	// assert
+	if noSelectorErr == nil {
+		t.Error("Unprotect without a selector should be refused.")
+	}
+	if allErr != nil {
+		t.Errorf("Unprotect with all should be accepted. %v", allErr)
+	}
+}
@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ecr"
+	"github.com/aws/aws-sdk-go/service/ecr/ecriface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// resourcePurging empties resources that CloudFormation refuses to delete while they hold data.
+type resourcePurging interface {
+	emptyBucket(ctx aws.Context, location stackLocation, bucket string) (int, error)
+	deleteImages(ctx aws.Context, location stackLocation, repository string) (int, error)
+	selectAccounts(accounts []*account)
+}
+
+// resourcePurger creates the clients of an account and region on first use. Accounts of the accounts file
+// are reached through their role, the others through the session. It is safe for concurrent use.
+type resourcePurger struct {
+	mutex    sync.Mutex
+	sess     *session.Session
+	accounts map[string]*session.Session // account id to the session of the account's role
+	s3       map[stackLocation]s3iface.S3API
+	ecr      map[stackLocation]ecriface.ECRAPI
+}
+
+func newResourcePurger(sess *session.Session) resourcePurging {
+	var result resourcePurging = &resourcePurger{
+		sess:     sess,
+		accounts: make(map[string]*session.Session),
+		s3:       make(map[stackLocation]s3iface.S3API),
+		ecr:      make(map[stackLocation]ecriface.ECRAPI),
+	}
+	return result
+}
+
+// selectAccounts makes the resources of these accounts purged through the account's role.
+func (client *resourcePurger) selectAccounts(accounts []*account) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	for _, acct := range accounts {
+		client.accounts[acct.AccountId] = assumeRole(client.sess, acct)
+	}
+}
+
+// sessionOf returns the session of an account. The caller holds the mutex.
+func (client *resourcePurger) sessionOf(accountId string) *session.Session {
+	if sess, exist := client.accounts[accountId]; exist {
+		return sess
+	}
+	return client.sess
+}
+
+func (client *resourcePurger) s3Client(location stackLocation) s3iface.S3API {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	if _, exist := client.s3[location]; !exist {
+		client.s3[location] = s3.New(client.sessionOf(location.accountId), &aws.Config{
+			Region: aws.String(location.region),
+		})
+	}
+	return client.s3[location]
+}
+
+func (client *resourcePurger) ecrClient(location stackLocation) ecriface.ECRAPI {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	if _, exist := client.ecr[location]; !exist {
+		client.ecr[location] = ecr.New(client.sessionOf(location.accountId), &aws.Config{
+			Region: aws.String(location.region),
+		})
+	}
+	return client.ecr[location]
+}
+
+// emptyBucket deletes every object version and delete marker of a bucket, and returns how many were deleted.
+// A bucket that no longer exists is already empty.
+func (client *resourcePurger) emptyBucket(ctx aws.Context, location stackLocation, bucket string) (int, error) {
+	s3Client := client.s3Client(location)
+	deleted := 0
+	input := &s3.ListObjectVersionsInput{
+		Bucket: aws.String(bucket),
+	}
+	for {
+		page, err := s3Client.ListObjectVersionsWithContext(ctx, input)
+		if err != nil {
+			if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchBucket {
+				return deleted, nil
+			}
+			return deleted, err
+		}
+
+		// A page holds at most 1000 versions and markers, the most DeleteObjects takes at once.
+		objects := make([]*s3.ObjectIdentifier, 0, len(page.Versions)+len(page.DeleteMarkers))
+		for _, version := range page.Versions {
+			objects = append(objects, &s3.ObjectIdentifier{Key: version.Key, VersionId: version.VersionId})
+		}
+		for _, marker := range page.DeleteMarkers {
+			objects = append(objects, &s3.ObjectIdentifier{Key: marker.Key, VersionId: marker.VersionId})
+		}
+		if len(objects) > 0 {
+			out, deleteErr := s3Client.DeleteObjectsWithContext(ctx, &s3.DeleteObjectsInput{
+				Bucket: aws.String(bucket),
+				Delete: &s3.Delete{
+					Objects: objects,
+					Quiet:   aws.Bool(true),
+				},
+			})
+			if deleteErr != nil {
+				return deleted, deleteErr
+			}
+			if len(out.Errors) > 0 {
+				return deleted, errors.New(fmt.Sprintf("Unable to delete %v from bucket %v: %v",
+					aws.StringValue(out.Errors[0].Key), bucket, aws.StringValue(out.Errors[0].Message)))
+			}
+			deleted = deleted + len(objects)
+		}
+
+		if !aws.BoolValue(page.IsTruncated) {
+			return deleted, nil
+		}
+		input.KeyMarker = page.NextKeyMarker
+		input.VersionIdMarker = page.NextVersionIdMarker
+	}
+}
+
+// deleteImages deletes every image of a repository, and returns how many were deleted.
+// A repository that no longer exists has no images.
+func (client *resourcePurger) deleteImages(ctx aws.Context, location stackLocation, repository string) (int, error) {
+	ecrClient := client.ecrClient(location)
+
+	// List everything before deleting, so deletions don't invalidate the paging token.
+	// An image is listed once per tag. Deleting it by digest removes all of its tags.
+	imageIds := make([]*ecr.ImageIdentifier, 0)
+	digests := make(map[string]bool)
+	input := &ecr.ListImagesInput{
+		RepositoryName: aws.String(repository),
+	}
+	for {
+		page, err := ecrClient.ListImagesWithContext(ctx, input)
+		if err != nil {
+			if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ecr.ErrCodeRepositoryNotFoundException {
+				return 0, nil
+			}
+			return 0, err
+		}
+		for _, imageId := range page.ImageIds {
+			digest := aws.StringValue(imageId.ImageDigest)
+			if digests[digest] {
+				continue
+			}
+			digests[digest] = true
+			imageIds = append(imageIds, &ecr.ImageIdentifier{ImageDigest: imageId.ImageDigest})
+		}
+		if page.NextToken == nil {
+			break
+		}
+		input.NextToken = page.NextToken
+	}
+
+	// BatchDeleteImage takes at most 100 images at once.
+	deleted := 0
+	for start := 0; start < len(imageIds); start += 100 {
+		end := start + 100
+		if end > len(imageIds) {
+			end = len(imageIds)
+		}
+		out, deleteErr := ecrClient.BatchDeleteImageWithContext(ctx, &ecr.BatchDeleteImageInput{
+			RepositoryName: aws.String(repository),
+			ImageIds:       imageIds[start:end],
+		})
+		if deleteErr != nil {
+			return deleted, deleteErr
+		}
+		for _, failure := range out.Failures {
+			if aws.StringValue(failure.FailureCode) != ecr.ImageFailureCodeImageNotFound {
+				return deleted, errors.New(fmt.Sprintf("Unable to delete image %v from repository %v: %v",
+					aws.StringValue(failure.ImageId.ImageDigest), repository, aws.StringValue(failure.FailureReason)))
+			}
+		}
+		deleted = deleted + len(out.ImageIds)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/spf13/viper"
+)
+
+// regionSelection is which regions the commands that go through every region cover.
+// Without explicit regions, every region enabled for the account is discovered and covered.
+type regionSelection struct {
+	regions      []string // explicit regions, nothing to discover
+	includeOptIn bool     // without regions, also the opt-in regions the account opted in to
+}
+
+// readRegionSelection reads the region flags. all-regions asks for discovery, which is what no regions mean anyway.
+func readRegionSelection(v *viper.Viper) (regionSelection, []string) {
+	selection := regionSelection{
+		regions:      v.GetStringSlice("regions"),
+		includeOptIn: v.GetBool("include-opt-in"),
+	}
+
+	errstrings := make([]string, 0)
+	if len(selection.regions) > 0 && v.GetBool("all-regions") {
+		errstrings = append(errstrings, "Please specify either regions or all-regions, not both.")
+	}
+	if len(selection.regions) > 0 && selection.includeOptIn {
+		errstrings = append(errstrings, "Please specify either regions or include-opt-in, not both.")
+	}
+	return selection, errstrings
+}
+
+// regionClients creates the client of a region on first use, and resolves the regions to fan out to on first need.
+// Commands working on a single stack never discover regions. Discovery goes through the session's region,
+// so it only returns regions of the session's partition. It is safe for concurrent use.
+type regionClients struct {
+	mutex         sync.Mutex
+	sessionRegion string
+	selection     regionSelection
+	newClient     func(region string) cloudformationiface.CloudFormationAPI
+	discover      func() ([]*ec2.Region, error)
+	clients       map[string]cloudformationiface.CloudFormationAPI
+	fanOut        []string // nil until resolved
+}
+
+func newRegionClients(sess *session.Session) *regionClients {
+	sessionRegion := aws.StringValue(sess.Config.Region)
+	return &regionClients{
+		sessionRegion: sessionRegion,
+		newClient: func(region string) cloudformationiface.CloudFormationAPI {
+			return newRegionClient(sess, region)
+		},
+		discover: func() ([]*ec2.Region, error) {
+			regions, err := ec2.New(sess).DescribeRegions(&ec2.DescribeRegionsInput{
+				AllRegions: aws.Bool(true),
+			})
+			if err != nil {
+				return nil, err
+			}
+			return regions.Regions, nil
+		},
+		clients: make(map[string]cloudformationiface.CloudFormationAPI),
+	}
+}
+
+// selectRegions sets the regions to fan out to. They are resolved again on next need.
+func (r *regionClients) selectRegions(selection regionSelection) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.selection = selection
+	r.fanOut = nil
+}
+
+// client returns the client of a region.
+func (r *regionClients) client(region string) cloudformationiface.CloudFormationAPI {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, exist := r.clients[region]; !exist {
+		r.clients[region] = r.newClient(region)
+	}
+	return r.clients[region]
+}
+
+// fanOutRegions returns the regions the commands that go through every region cover.
+// When discovery fails, only the session's region is covered.
+func (r *regionClients) fanOutRegions() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.fanOut != nil {
+		return r.fanOut
+	}
+
+	if len(r.selection.regions) > 0 {
+		r.fanOut = r.samePartition(r.selection.regions)
+	} else {
+		r.fanOut = r.discoverRegions()
+	}
+	return r.fanOut
+}
+
+// deployRegions returns the regions commands deploying stacks cover: the selected regions, or the session's region.
+// Unlike fanOutRegions, regions are never discovered, so stacks only land where asked.
+func (r *regionClients) deployRegions() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if len(r.selection.regions) > 0 {
+		return r.samePartition(r.selection.regions)
+	}
+	return r.sessionRegions()
+}
+
+func (r *regionClients) discoverRegions() []string {
+	regions, err := r.discover()
+	if err != nil {
+		fmt.Printf("Unable to discover regions: %v\nFalling back to the session region.\n", err)
+		return r.sessionRegions()
+	}
+
+	result := make([]string, 0, len(regions))
+	for _, region := range regions {
+		switch aws.StringValue(region.OptInStatus) {
+		case "opt-in-not-required":
+		case "opted-in":
+			if !r.selection.includeOptIn {
+				continue
+			}
+		default:
+			continue
+		}
+		result = append(result, aws.StringValue(region.RegionName))
+	}
+	return result
+}
+
+func (r *regionClients) sessionRegions() []string {
+	if r.sessionRegion == "" {
+		fmt.Println("No region configured. Please set a region or use --regions.")
+		return []string{}
+	}
+	return []string{r.sessionRegion}
+}
+
+// samePartition drops the regions outside the partition of the session's region, e.g. aws-cn regions
+// with aws-us-gov credentials. Credentials of one partition are not valid in another.
+func (r *regionClients) samePartition(regions []string) []string {
+	sessionPartition := partitionOf(r.sessionRegion)
+	if sessionPartition == "" {
+		return regions
+	}
+
+	result := make([]string, 0, len(regions))
+	for _, region := range regions {
+		if partition := partitionOf(region); partition != "" && partition != sessionPartition {
+			fmt.Printf("Skipping region %v: it is in partition %v, the session is in %v.\n", region, partition, sessionPartition)
+			continue
+		}
+		result = append(result, region)
+	}
+	return result
+}
+
+// partitionOf returns the partition of a region, e.g. aws, aws-us-gov or aws-cn, or an empty string when unknown.
+func partitionOf(region string) string {
+	partition, found := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region)
+	if !found {
+		return ""
+	}
+	return partition.ID()
+}
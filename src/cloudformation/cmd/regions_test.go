@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/spf13/viper"
+)
+
+func testRegionClients(discover func() ([]*ec2.Region, error)) *regionClients {
+	return &regionClients{
+		sessionRegion: "us-east-2",
+		discover:      discover,
+	}
+}
+
+func TestReadRegionSelection(t *testing.T) {
+	cases := []struct {
+		values   map[string]interface{}
+		regions  int
+		optIn    bool
+		errCount int
+	}{
+		{map[string]interface{}{}, 0, false, 0},
+		{map[string]interface{}{"all-regions": true}, 0, false, 0},
+		{map[string]interface{}{"all-regions": true, "include-opt-in": true}, 0, true, 0},
+		{map[string]interface{}{"regions": []string{"us-east-1", "eu-west-1"}}, 2, false, 0},
+		{map[string]interface{}{"regions": []string{"us-east-1"}, "all-regions": true}, 1, false, 1},
+		{map[string]interface{}{"regions": []string{"us-east-1"}, "include-opt-in": true}, 1, true, 1},
+	}
+
+	for i, c := range cases {
+		// arrange
+		localViper := viper.New()
+		for key, value := range c.values {
+			localViper.Set(key, value)
+		}
+
+		// act
+		selection, errstrings := readRegionSelection(localViper)
+
+		// assert
+		if len(selection.regions) != c.regions || selection.includeOptIn != c.optIn || len(errstrings) != c.errCount {
+			t.Errorf("Case %v: unexpected selection %#v, errors %v", i, selection, errstrings)
+		}
+	}
+}
+
+func TestFanOutRegions_Discovery(t *testing.T) {
+	// arrange
+	regions := testRegionClients(func() ([]*ec2.Region, error) {
+		return []*ec2.Region{
+			&ec2.Region{RegionName: aws.String("us-east-1"), OptInStatus: aws.String("opt-in-not-required")},
+			&ec2.Region{RegionName: aws.String("af-south-1"), OptInStatus: aws.String("opted-in")},
+			&ec2.Region{RegionName: aws.String("me-south-1"), OptInStatus: aws.String("not-opted-in")},
+		}, nil
+	})
+	cases := []struct {
+		selection regionSelection
+		expected  int
+	}{
+		{regionSelection{}, 1},
+		{regionSelection{includeOptIn: true}, 2},
+		{regionSelection{regions: []string{"eu-west-1", "eu-west-2", "eu-west-3"}}, 3},
+	}
+
+	for i, c := range cases {
+		// act
+		regions.selectRegions(c.selection)
+		fanOut := regions.fanOutRegions()
+
+		// assert
+		if len(fanOut) != c.expected {
+			t.Errorf("Case %v: expected %v regions, got %#v", i, c.expected, fanOut)
+		}
+	}
+}
+
+func TestFanOutRegions_DiscoveryFailure(t *testing.T) {
+	// arrange
+	regions := testRegionClients(func() ([]*ec2.Region, error) {
+		return nil, errors.New("UnauthorizedOperation")
+	})
+
+	// act
+	fanOut := regions.fanOutRegions()
+
+	// assert
+	if len(fanOut) != 1 || fanOut[0] != "us-east-2" {
+		t.Errorf("Failed discovery should fall back to the session region. %#v", fanOut)
+	}
+}
+
+func TestFanOutRegions_SessionPartitionOnly(t *testing.T) {
+	// arrange
+	regions := testRegionClients(nil)
+	regions.sessionRegion = "us-gov-west-1"
+	regions.selectRegions(regionSelection{regions: []string{"us-gov-east-1", "cn-north-1", "us-east-1"}})
+
+	// act
+	fanOut := regions.fanOutRegions()
+
+	// assert
+	if len(fanOut) != 1 || fanOut[0] != "us-gov-east-1" {
+		t.Errorf("Only regions of the session partition should be covered. %#v", fanOut)
+	}
+}
+
+func TestGetDeployRegions_Defaults(t *testing.T) {
+	// arrange
+	discover := func() ([]*ec2.Region, error) {
+		return []*ec2.Region{
+			&ec2.Region{RegionName: aws.String("us-east-1"), OptInStatus: aws.String("opt-in-not-required")},
+			&ec2.Region{RegionName: aws.String("eu-west-1"), OptInStatus: aws.String("opt-in-not-required")},
+		}, nil
+	}
+	accountClients := func() *regionClients {
+		regions := testRegionClients(discover)
+		regions.newClient = func(region string) cloudformationiface.CloudFormationAPI { return nil }
+		regions.clients = make(map[string]cloudformationiface.CloudFormationAPI)
+		return regions
+	}
+	target := &cfnManager{
+		regions: testRegionClients(discover),
+		accounts: []*account{
+			&account{AccountId: "111111111111", Regions: []string{"eu-west-3"}},
+			&account{AccountId: "222222222222"},
+		},
+		accountRegions: map[string]*regionClients{
+			"111111111111": accountClients(),
+			"222222222222": accountClients(),
+		},
+	}
+	cases := []struct {
+		selection regionSelection
+		expected  map[string]string
+	}{
+		{regionSelection{}, map[string]string{"111111111111": "eu-west-3", "222222222222": "us-east-2"}},
+		{regionSelection{regions: []string{"ap-south-1"}}, map[string]string{"111111111111": "eu-west-3", "222222222222": "ap-south-1"}},
+	}
+
+	for i, c := range cases {
+		target.selectRegions(c.selection)
+		for accountId, expected := range c.expected {
+			// act
+			accountManager, _ := target.inAccount(accountId)
+			regions := accountManager.getDeployRegions()
+
+			// assert
+			if len(regions) != 1 || regions[0] != expected {
+				t.Errorf("Case %v: account %v should deploy to %v only, got %#v", i, accountId, expected, regions)
+			}
+		}
+	}
+}
+
+func TestPartitionOf(t *testing.T) {
+	cases := map[string]string{
+		"us-east-1":     "aws",
+		"us-gov-west-1": "aws-us-gov",
+		"cn-north-1":    "aws-cn",
+		"":              "",
+	}
+
+	for region, expected := range cases {
+		if partition := partitionOf(region); partition != expected {
+			t.Errorf("Region %q: expected partition %q, got %q", region, expected, partition)
+		}
+	}
+}
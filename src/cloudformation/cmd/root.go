@@ -1,11 +1,18 @@
 package cmd
 
 import (
+	"aws-machete/src/awssession"
+	"context"
+	"errors"
 	"fmt"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 )
 
 func (cm *CommandManagement) rootCmdRun(cmd *cobra.Command, args []string) {
@@ -16,9 +23,8 @@ var rootCmdLong = `CloudFormation cli utility that can can do more sophisticated
 
 func initRootCmd() *CommandManagement {
 	cm := &CommandManagement{
-		config:     &config{},
-		cfnManager: newCfnClient(),
-		viper:      viper.New(),
+		config: &config{},
+		viper:  viper.New(),
 	}
 	cm.root = &cobra.Command{
 		Use:   "cloudformation",
@@ -47,18 +53,55 @@ func initRootCmd() *CommandManagement {
 				}
 			}
 
+			// The aws clients are built once the session flags are known.
+			options, errstrings := awssession.ReadOptions(cm.viper)
+			if len(errstrings) > 0 {
+				return errors.New(strings.Join(errstrings, "\n"))
+			}
+			sess, sessErr := awssession.New(options)
+			if sessErr != nil {
+				return sessErr
+			}
+			cm.cfnManager = newCfnClient(sess)
+			cm.artifactManager = newArtifactClient(sess)
+			cm.resourcePurger = newResourcePurger(sess)
+
 			config := cm.config
-			config.timeout = cm.viper.GetInt("timeout")
+			config.timeout = cm.viper.GetInt("wait")
+			config.cancelOnTimeout = cm.viper.GetBool("cancel-on-timeout")
+			cm.ctx = newCommandContext(config.timeout)
 			modeString := cm.viper.GetString("mode")
 			fmt.Printf("Command execution mode: %v\n", modeString)
 			config.mode = ParseMode(modeString)
 
+			selection, regionErrs := readRegionSelection(cm.viper)
+			if len(regionErrs) > 0 {
+				return errors.New(strings.Join(regionErrs, "\n"))
+			}
+			if accountsFile := cm.viper.GetString("accounts-file"); accountsFile != "" {
+				accounts, accountsErr := readAccounts(accountsFile)
+				if accountsErr != nil {
+					return accountsErr
+				}
+				fmt.Printf("Accounts: %v from %v\n", len(accounts), accountsFile)
+				cm.cfnManager.selectAccounts(accounts)
+				cm.resourcePurger.selectAccounts(accounts)
+			}
+			cm.cfnManager.selectRegions(selection)
+
 			return nil
 		},
 	}
 	// app flags, to be optionally overriden by viper.
 	cm.root.PersistentFlags().StringP("mode", "m", "interactive", "Modes of command execution. Valid options are: noninteractive, changesetonly, dry, interactive.")
 	cm.root.PersistentFlags().IntP("wait", "w", -1, "Time out in seconds to wait for the operation to complete. -1 means wait forever.")
+	cm.root.PersistentFlags().Bool("cancel-on-timeout", false, "Cancel a stack update that is still in progress when the wait time out is reached.")
+	cm.root.PersistentFlags().StringSlice("regions", nil, "Regions that commands going through every region cover. Defaults to every region enabled for the account.")
+	cm.root.PersistentFlags().Bool("all-regions", false, "Cover every region enabled for the account in commands going through every region. This is the default without regions.")
+	cm.root.PersistentFlags().Bool("include-opt-in", false, "Also cover the opt-in regions the account opted in to when no regions are given.")
+	cm.root.PersistentFlags().String("accounts-file", "", "Yaml file of the accounts to cover through an assumed role instead of the session's account.")
+
+	awssession.AddFlags(cm.root.PersistentFlags())
 
 	// viper flags.
 	cm.root.PersistentFlags().StringP("config-path", "c", "", "Config file to supply flags / parameters with.")
@@ -66,10 +109,46 @@ func initRootCmd() *CommandManagement {
 
 	cm.initUpdateCmd()
 	cm.initDeleteAllCmd()
+	cm.initDeleteCmd()
 	cm.initEnsureCmd()
+	cm.initPackageCmd()
+	cm.initCopyCmd()
+	cm.initExportCmd()
+	cm.initDriftCmd()
+	cm.initListCmd()
+	cm.initPolicyCmd()
+	cm.initProtectCmd()
+	cm.initImportCmd()
 	cm.viper.SetKeysCaseSensitive(true)
 
 	return cm
 }
 
+// newCommandContext returns a context that is cancelled on SIGINT / SIGTERM or after timeout seconds.
+// A negative timeout means no deadline.
+func newCommandContext(timeout int) context.Context {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeout < 0 {
+		ctx, cancel = context.WithCancel(context.Background())
+	} else {
+		ctx, cancel = context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-signals:
+			fmt.Printf("Received %v. Cancelling...\n", sig)
+		case <-ctx.Done():
+		}
+		// Restore the default behaviour so a second signal terminates immediately.
+		signal.Stop(signals)
+		cancel()
+	}()
+
+	return ctx
+}
+
 var CommandManagerInstance CommandManager = initRootCmd()
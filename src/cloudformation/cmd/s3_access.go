@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+type artifactManagement interface {
+	upload(ctx aws.Context, bucket string, key string, content []byte) (string, error)
+}
+
+type artifactManager struct {
+	s3 s3iface.S3API
+}
+
+func newArtifactClient(sess *session.Session) artifactManagement {
+	var result artifactManagement = &artifactManager{
+		s3: s3.New(sess),
+	}
+	return result
+}
+
+// upload puts content in the bucket unless an object with the same key already exists,
+// and returns the https url of the object. Without s3:ListBucket, s3 answers 403 instead of 404
+// for a missing object, so both mean the object is uploaded.
+func (client *artifactManager) upload(ctx aws.Context, bucket string, key string, content []byte) (string, error) {
+	_, headErr := client.s3.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if headErr != nil {
+		aerr, ok := headErr.(awserr.RequestFailure)
+		if !ok || (aerr.StatusCode() != 404 && aerr.StatusCode() != 403) {
+			return "", headErr
+		}
+
+		_, putErr := client.s3.PutObjectWithContext(ctx, &s3.PutObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+			Body:   bytes.NewReader(content),
+		})
+		if putErr != nil {
+			return "", errors.New(fmt.Sprintf("Unable to upload %v to bucket %v: %v\nUploading needs s3:PutObject on the bucket, and s3:ListBucket to skip objects already uploaded.", key, bucket, putErr))
+		}
+	}
+
+	// Let the sdk work out the endpoint of the bucket. This keeps the url right for every partition.
+	req, _ := client.s3.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if buildErr := req.Build(); buildErr != nil {
+		return "", buildErr
+	}
+	return req.HTTPRequest.URL.String(), nil
+}
+
+// contentHashKey returns an object key derived from the content so unchanged artifacts are uploaded only once.
+func contentHashKey(content []byte, extension string) string {
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:]) + extension
+}
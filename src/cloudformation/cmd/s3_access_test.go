@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/awstesting/unit"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestUpload_ForbiddenHeadUploads(t *testing.T) {
+	// arrange
+	api := &mockS3API{
+		S3API: s3.New(unit.Session),
+		headObjectStub: func(input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+			return nil, awserr.NewRequestFailure(awserr.New("Forbidden", "Forbidden", nil), 403, "request-id")
+		},
+	}
+	target := &artifactManager{s3: api}
+
+	// act
+	url, err := target.upload(context.Background(), "bucket", "key.template", []byte("{}"))
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(api.puts) != 1 || !strings.HasSuffix(url, "/key.template") {
+		t.Errorf("An object s3 refuses to describe should be uploaded. %v %v", api.puts, url)
+	}
+}
+
+func TestUpload_PutDenied(t *testing.T) {
+	// arrange
+	api := &mockS3API{
+		S3API: s3.New(unit.Session),
+		headObjectStub: func(input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+			return nil, awserr.NewRequestFailure(awserr.New("Forbidden", "Forbidden", nil), 403, "request-id")
+		},
+		putObjectErr: awserr.NewRequestFailure(awserr.New("AccessDenied", "Access Denied", nil), 403, "request-id"),
+	}
+	target := &artifactManager{s3: api}
+
+	// act
+	_, err := target.upload(context.Background(), "bucket", "key.template", []byte("{}"))
+
+	// assert
+	if err == nil || !strings.Contains(err.Error(), "s3:PutObject") {
+		t.Errorf("A denied upload should tell the permissions needed. %v", err)
+	}
+}
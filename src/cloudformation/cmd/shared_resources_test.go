@@ -1,61 +1,148 @@
 package cmd
 
 import (
+	"io"
+	"time"
+
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 )
 
+// mockCfnAPI stubs the raw CloudFormation client. Calls without a stub panic on the nil embedded interface.
+type mockCfnAPI struct {
+	cloudformationiface.CloudFormationAPI
+	describeStackEventsStub func(input *cloudformation.DescribeStackEventsInput) (*cloudformation.DescribeStackEventsOutput, error)
+	describeStacksStub      func(input *cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error)
+	getTemplateStub         func(input *cloudformation.GetTemplateInput) (*cloudformation.GetTemplateOutput, error)
+	listStackResourcesStub  func(input *cloudformation.ListStackResourcesInput) (*cloudformation.ListStackResourcesOutput, error)
+}
+
+func (api *mockCfnAPI) DescribeStacksWithContext(ctx aws.Context, input *cloudformation.DescribeStacksInput, opts ...request.Option) (*cloudformation.DescribeStacksOutput, error) {
+	return api.describeStacksStub(input)
+}
+
+// DescribeStacksPagesWithContext pages through describeStacksStub, following NextToken.
+func (api *mockCfnAPI) DescribeStacksPagesWithContext(ctx aws.Context, input *cloudformation.DescribeStacksInput, fn func(*cloudformation.DescribeStacksOutput, bool) bool, opts ...request.Option) error {
+	for {
+		page, err := api.describeStacksStub(input)
+		if err != nil {
+			return err
+		}
+		if !fn(page, page.NextToken == nil) || page.NextToken == nil {
+			return nil
+		}
+		input = &cloudformation.DescribeStacksInput{NextToken: page.NextToken}
+	}
+}
+
+func (api *mockCfnAPI) DescribeStackEventsWithContext(ctx aws.Context, input *cloudformation.DescribeStackEventsInput, opts ...request.Option) (*cloudformation.DescribeStackEventsOutput, error) {
+	return api.describeStackEventsStub(input)
+}
+
+func (api *mockCfnAPI) GetTemplateWithContext(ctx aws.Context, input *cloudformation.GetTemplateInput, opts ...request.Option) (*cloudformation.GetTemplateOutput, error) {
+	return api.getTemplateStub(input)
+}
+
+func (api *mockCfnAPI) ListStackResourcesWithContext(ctx aws.Context, input *cloudformation.ListStackResourcesInput, opts ...request.Option) (*cloudformation.ListStackResourcesOutput, error) {
+	return api.listStackResourcesStub(input)
+}
+
 type mockCfnManager struct {
 	params                 []*cloudformation.Parameter
 	tags                   []*cloudformation.Tag
+	retainResources        []*string
+	changeSetOptions       *changeSetOptions
 	getStackStub           func(stackName *string) (*cloudformation.Stack, error)
 	getStackTemplateStub   func(stackName *string) (*string, error)
-	createChangeSetStub    func(stackName *string, params []*cloudformation.Parameter, tags []*cloudformation.Tag, templateBody *string, changeSetType string) (*cloudformation.CreateChangeSetOutput, error)
+	createChangeSetStub    func(stackName *string, params []*cloudformation.Parameter, tags []*cloudformation.Tag, template *cfnTemplate, changeSetType string) (*cloudformation.CreateChangeSetOutput, error)
+	describeChangeSetStub  func(stackName *string, csName *string) (*cloudformation.DescribeChangeSetOutput, error)
+	discardChangeSetStub   func(stackName *string, csName *string, changeSetType string) error
 	executeChangeSetStub   func(stackname *string, csName *string) error
-	getTemplateSummaryStub func(templateBody *string) (*cloudformation.GetTemplateSummaryOutput, error)
+	cancelUpdateStackStub  func(stackName *string) error
+	getTemplateSummaryStub func(template *cfnTemplate) (*cloudformation.GetTemplateSummaryOutput, error)
 	getAllStub             func(stackChannel chan *cloudformation.Stack, errChannel chan error)
 	deleteStub             func(stackName *string) error
+	inRegionStub           func(region string) cfnManagement
+	detectDriftStub        func(stackName *string) (*stackDrift, error)
+	waitStackDeletedStub   func(stackName *string) error
+	listExportsStub        func() ([]*cloudformation.Export, error)
+	listImportsStub        func(exportName *string) ([]*string, error)
+	listStackResourcesStub func(stackName *string) ([]*cloudformation.StackResourceSummary, error)
+	setProtectionStub      func(stackName *string, enabled bool) error
+	getStackPolicyStub     func(stackName *string) (*string, error)
+	setStackPolicyStub     func(stackName *string, policy *string) error
+	inAccountStub          func(accountId string) cfnManagement
 	regionCount            int
+	regionSelection        regionSelection
+	regions                []string
+	deployRegions          []string
+	accounts               []*account
 }
 
-func (mcm *mockCfnManager) getStack(stackName *string) (*cloudformation.Stack, error) {
+func (mcm *mockCfnManager) getStack(ctx aws.Context, stackName *string) (*cloudformation.Stack, error) {
 	if mcm.getStackStub == nil {
 		return &cloudformation.Stack{}, nil
 	}
 	return mcm.getStackStub(stackName)
 }
 
-func (mcm *mockCfnManager) getStackTemplate(stackName *string) (*string, error) {
+func (mcm *mockCfnManager) getStackTemplate(ctx aws.Context, stackName *string) (*string, error) {
 	if mcm.getStackTemplateStub == nil {
 		return aws.String(""), nil
 	}
 	return mcm.getStackTemplateStub(stackName)
 }
 
-func (mcm *mockCfnManager) createChangeSet(stackName *string, params []*cloudformation.Parameter, tags []*cloudformation.Tag, templateBody *string, changeSetType string) (*cloudformation.CreateChangeSetOutput, error) {
+func (mcm *mockCfnManager) createChangeSet(ctx aws.Context, stackName *string, params []*cloudformation.Parameter, tags []*cloudformation.Tag, template *cfnTemplate, changeSetType string, options *changeSetOptions) (*cloudformation.CreateChangeSetOutput, error) {
 	mcm.tags = tags
 	mcm.params = params
+	mcm.changeSetOptions = options
 	if mcm.createChangeSetStub == nil {
 		return &cloudformation.CreateChangeSetOutput{}, nil
 	}
-	return mcm.createChangeSetStub(stackName, params, tags, templateBody, changeSetType)
+	return mcm.createChangeSetStub(stackName, params, tags, template, changeSetType)
+}
+
+func (mcm *mockCfnManager) describeChangeSet(ctx aws.Context, stackName *string, csName *string) (*cloudformation.DescribeChangeSetOutput, error) {
+	if mcm.describeChangeSetStub == nil {
+		return &cloudformation.DescribeChangeSetOutput{}, nil
+	}
+	return mcm.describeChangeSetStub(stackName, csName)
+}
+
+func (mcm *mockCfnManager) discardChangeSet(ctx aws.Context, stackName *string, csName *string, changeSetType string) error {
+	if mcm.discardChangeSetStub == nil {
+		return nil
+	}
+	return mcm.discardChangeSetStub(stackName, csName, changeSetType)
 }
 
-func (mcm *mockCfnManager) executeChangeSet(stackname *string, csName *string) error {
+func (mcm *mockCfnManager) executeChangeSet(ctx aws.Context, stackname *string, csName *string, events io.Writer) error {
 	if mcm.executeChangeSetStub == nil {
 		return nil
 	}
 	return mcm.executeChangeSetStub(stackname, csName)
 }
 
-func (mcm *mockCfnManager) getTemplateSummary(templateBody *string) (*cloudformation.GetTemplateSummaryOutput, error) {
+func (mcm *mockCfnManager) cancelUpdateStack(ctx aws.Context, stackName *string) error {
+	if mcm.cancelUpdateStackStub == nil {
+		return nil
+	}
+	return mcm.cancelUpdateStackStub(stackName)
+}
+
+func (mcm *mockCfnManager) getTemplateSummary(ctx aws.Context, template *cfnTemplate) (*cloudformation.GetTemplateSummaryOutput, error) {
 	if mcm.getTemplateSummaryStub == nil {
 		return &cloudformation.GetTemplateSummaryOutput{}, nil
 	}
-	return mcm.getTemplateSummaryStub(templateBody)
+	return mcm.getTemplateSummaryStub(template)
 }
 
-func (mcm *mockCfnManager) getAll(stackChannel chan *cloudformation.Stack, errChannel chan error) {
+func (mcm *mockCfnManager) getAll(ctx aws.Context, stackChannel chan *cloudformation.Stack, errChannel chan error) {
 	if mcm.getAllStub != nil {
 		mcm.getAllStub(stackChannel, errChannel)
 	}
@@ -65,14 +152,172 @@ func (mcm *mockCfnManager) getAll(stackChannel chan *cloudformation.Stack, errCh
 	}
 }
 
+func (mcm *mockCfnManager) selectRegions(selection regionSelection) {
+	mcm.regionSelection = selection
+}
+
+func (mcm *mockCfnManager) getRegions() []string {
+	return mcm.regions
+}
+
+func (mcm *mockCfnManager) getDeployRegions() []string {
+	return mcm.deployRegions
+}
+
+func (mcm *mockCfnManager) selectAccounts(accounts []*account) {
+	mcm.accounts = accounts
+}
+
+func (mcm *mockCfnManager) accountIds() []string {
+	accountIds := make([]string, 0, len(mcm.accounts))
+	for _, acct := range mcm.accounts {
+		accountIds = append(accountIds, acct.AccountId)
+	}
+	return accountIds
+}
+
+func (mcm *mockCfnManager) inAccount(accountId string) (cfnManagement, error) {
+	if mcm.inAccountStub == nil {
+		return mcm, nil
+	}
+	return mcm.inAccountStub(accountId), nil
+}
+
 func (mcm *mockCfnManager) getRegionCount() int {
 	return mcm.regionCount
 }
 
-func (mcm *mockCfnManager) delete(stackArn *string) error {
+func (mcm *mockCfnManager) detectDrift(ctx aws.Context, stackName *string) (*stackDrift, error) {
+	if mcm.detectDriftStub == nil {
+		return &stackDrift{status: &cloudformation.DescribeStackDriftDetectionStatusOutput{}}, nil
+	}
+	return mcm.detectDriftStub(stackName)
+}
+
+func (mcm *mockCfnManager) inRegion(region string) cfnManagement {
+	if mcm.inRegionStub == nil {
+		return mcm
+	}
+	return mcm.inRegionStub(region)
+}
+
+func (mcm *mockCfnManager) delete(ctx aws.Context, stackArn *string, retainResources []*string) error {
+	if len(retainResources) > 0 {
+		mcm.retainResources = retainResources
+	}
 	if mcm.deleteStub != nil {
 		return mcm.deleteStub(stackArn)
 	}
 
 	return nil
 }
+
+func (mcm *mockCfnManager) waitStackDeleted(ctx aws.Context, stackArn *string, since time.Time, events io.Writer) error {
+	if mcm.waitStackDeletedStub == nil {
+		return nil
+	}
+	return mcm.waitStackDeletedStub(stackArn)
+}
+
+func (mcm *mockCfnManager) listExports(ctx aws.Context) ([]*cloudformation.Export, error) {
+	if mcm.listExportsStub == nil {
+		return []*cloudformation.Export{}, nil
+	}
+	return mcm.listExportsStub()
+}
+
+func (mcm *mockCfnManager) listImports(ctx aws.Context, exportName *string) ([]*string, error) {
+	if mcm.listImportsStub == nil {
+		return []*string{}, nil
+	}
+	return mcm.listImportsStub(exportName)
+}
+
+func (mcm *mockCfnManager) setTerminationProtection(ctx aws.Context, stackName *string, enabled bool) error {
+	if mcm.setProtectionStub == nil {
+		return nil
+	}
+	return mcm.setProtectionStub(stackName, enabled)
+}
+
+func (mcm *mockCfnManager) getStackPolicy(ctx aws.Context, stackName *string) (*string, error) {
+	if mcm.getStackPolicyStub == nil {
+		return nil, nil
+	}
+	return mcm.getStackPolicyStub(stackName)
+}
+
+func (mcm *mockCfnManager) setStackPolicy(ctx aws.Context, stackName *string, policy *string) error {
+	if mcm.setStackPolicyStub == nil {
+		return nil
+	}
+	return mcm.setStackPolicyStub(stackName, policy)
+}
+
+func (mcm *mockCfnManager) listStackResources(ctx aws.Context, stackName *string) ([]*cloudformation.StackResourceSummary, error) {
+	if mcm.listStackResourcesStub == nil {
+		return []*cloudformation.StackResourceSummary{}, nil
+	}
+	return mcm.listStackResourcesStub(stackName)
+}
+
+type mockArtifactManager struct {
+	uploads    map[string][]byte
+	uploadStub func(bucket string, key string, content []byte) (string, error)
+}
+
+func (mam *mockArtifactManager) upload(ctx aws.Context, bucket string, key string, content []byte) (string, error) {
+	if mam.uploads == nil {
+		mam.uploads = make(map[string][]byte)
+	}
+	mam.uploads[bucket+"/"+key] = content
+	if mam.uploadStub == nil {
+		return "https://" + bucket + ".s3.amazonaws.com/" + key, nil
+	}
+	return mam.uploadStub(bucket, key, content)
+}
+
+// mockS3API stubs object reads and writes. Other calls go to the embedded client, e.g. to build urls.
+type mockS3API struct {
+	s3iface.S3API
+	headObjectStub func(input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error)
+	puts           []string
+	putObjectErr   error
+}
+
+func (api *mockS3API) HeadObjectWithContext(ctx aws.Context, input *s3.HeadObjectInput, opts ...request.Option) (*s3.HeadObjectOutput, error) {
+	return api.headObjectStub(input)
+}
+
+func (api *mockS3API) PutObjectWithContext(ctx aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error) {
+	api.puts = append(api.puts, aws.StringValue(input.Key))
+	if api.putObjectErr != nil {
+		return nil, api.putObjectErr
+	}
+	return &s3.PutObjectOutput{}, nil
+}
+
+type mockResourcePurger struct {
+	emptied          []string
+	emptyBucketStub  func(region string, bucket string) (int, error)
+	deleteImagesStub func(region string, repository string) (int, error)
+}
+
+func (mrp *mockResourcePurger) emptyBucket(ctx aws.Context, location stackLocation, bucket string) (int, error) {
+	mrp.emptied = append(mrp.emptied, bucket)
+	if mrp.emptyBucketStub == nil {
+		return 0, nil
+	}
+	return mrp.emptyBucketStub(location.region, bucket)
+}
+
+func (mrp *mockResourcePurger) deleteImages(ctx aws.Context, location stackLocation, repository string) (int, error) {
+	mrp.emptied = append(mrp.emptied, repository)
+	if mrp.deleteImagesStub == nil {
+		return 0, nil
+	}
+	return mrp.deleteImagesStub(location.region, repository)
+}
+
+func (mrp *mockResourcePurger) selectAccounts(accounts []*account) {
+}
@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// retainedResource is a resource left behind by a deletion retry, to be cleaned up by hand.
+type retainedResource struct {
+	Stack      string `yaml:"stack"`
+	Account    string `yaml:"account"`
+	Region     string `yaml:"region"`
+	LogicalId  string `yaml:"logical-id"`
+	Type       string `yaml:"type"`
+	PhysicalId string `yaml:"physical-id"`
+	Reason     string `yaml:"reason"`
+}
+
+// stackDeleter deletes stacks and waits for them. With retainFailed, a stack ending in DELETE_FAILED
+// is deleted once more leaving the resources that failed in place. With a purger, buckets and repositories
+// of the stack are emptied before the deletion. It is safe for concurrent use.
+type stackDeleter struct {
+	cfnManager   cfnManagement
+	retainFailed bool
+	purger       resourcePurging
+	events       io.Writer // stack events are printed here while waiting, unless nil
+
+	mutex    sync.Mutex
+	retained []*retainedResource
+}
+
+// deleteStack deletes a stack by id and waits until it is gone.
+func (d *stackDeleter) deleteStack(ctx context.Context, stackId *string) error {
+	if d.purger != nil {
+		if purgeErr := d.purgeStack(ctx, stackId); purgeErr != nil {
+			return purgeErr
+		}
+	}
+
+	startTime := time.Now()
+	if delErr := d.cfnManager.delete(ctx, stackId, nil); delErr != nil {
+		return delErr
+	}
+	waitErr := d.cfnManager.waitStackDeleted(ctx, stackId, startTime, d.events)
+	if waitErr == nil || !d.retainFailed || ctx.Err() != nil {
+		return waitErr
+	}
+
+	stack, stackErr := d.cfnManager.getStack(ctx, stackId)
+	if stackErr != nil || stack == nil || aws.StringValue(stack.StackStatus) != cloudformation.StackStatusDeleteFailed {
+		return waitErr
+	}
+	parsed, arnErr := parseArn(stackId)
+	if arnErr != nil {
+		return arnErr
+	}
+	resources, resourcesErr := d.cfnManager.listStackResources(ctx, stackId)
+	if resourcesErr != nil {
+		return resourcesErr
+	}
+
+	retainResources := make([]*string, 0)
+	retained := make([]*retainedResource, 0)
+	for _, resource := range resources {
+		if aws.StringValue(resource.ResourceStatus) != cloudformation.ResourceStatusDeleteFailed {
+			continue
+		}
+		retainResources = append(retainResources, resource.LogicalResourceId)
+		retained = append(retained, &retainedResource{
+			Stack:      aws.StringValue(stack.StackName),
+			Account:    parsed.AccountID,
+			Region:     parsed.Region,
+			LogicalId:  aws.StringValue(resource.LogicalResourceId),
+			Type:       aws.StringValue(resource.ResourceType),
+			PhysicalId: aws.StringValue(resource.PhysicalResourceId),
+			Reason:     aws.StringValue(resource.ResourceStatusReason),
+		})
+	}
+	if len(retainResources) == 0 {
+		return waitErr
+	}
+
+	fmt.Printf("Retrying deletion of %v retaining: %v\n", aws.StringValue(stack.StackName), aws.StringValueSlice(retainResources))
+	startTime = time.Now()
+	if delErr := d.cfnManager.delete(ctx, stackId, retainResources); delErr != nil {
+		return delErr
+	}
+	if retryErr := d.cfnManager.waitStackDeleted(ctx, stackId, startTime, d.events); retryErr != nil {
+		return retryErr
+	}
+
+	d.mutex.Lock()
+	d.retained = append(d.retained, retained...)
+	d.mutex.Unlock()
+	return nil
+}
+
+// purgeStack empties the s3 buckets and ecr repositories of a stack and of its nested stacks.
+// Resources the template doesn't delete with the stack are left alone: they survive the deletion, and so should their data.
+func (d *stackDeleter) purgeStack(ctx context.Context, stackId *string) error {
+	parsed, arnErr := parseArn(stackId)
+	if arnErr != nil {
+		return arnErr
+	}
+	location := stackLocation{accountId: parsed.AccountID, region: parsed.Region}
+	template, templateErr := d.cfnManager.getStackTemplate(ctx, stackId)
+	if templateErr != nil {
+		return templateErr
+	}
+	kept, keptErr := keptResources(aws.StringValue(template))
+	if keptErr != nil {
+		return errors.New(fmt.Sprintf("Unable to read the deletion policies of %v: %v", aws.StringValue(stackId), keptErr))
+	}
+	resources, resourcesErr := d.cfnManager.listStackResources(ctx, stackId)
+	if resourcesErr != nil {
+		return resourcesErr
+	}
+
+	for _, resource := range resources {
+		physicalId := aws.StringValue(resource.PhysicalResourceId)
+		if physicalId == "" || aws.StringValue(resource.ResourceStatus) == cloudformation.ResourceStatusDeleteComplete {
+			continue
+		}
+		if policy, exist := kept[aws.StringValue(resource.LogicalResourceId)]; exist {
+			fmt.Printf("Not purging %v (%v): DeletionPolicy is %v.\n", aws.StringValue(resource.LogicalResourceId), physicalId, policy)
+			continue
+		}
+
+		switch aws.StringValue(resource.ResourceType) {
+		case nestedStackResourceType:
+			if purgeErr := d.purgeStack(ctx, resource.PhysicalResourceId); purgeErr != nil {
+				return purgeErr
+			}
+		case "AWS::S3::Bucket":
+			count, purgeErr := d.purger.emptyBucket(ctx, location, physicalId)
+			if purgeErr != nil {
+				return errors.New(fmt.Sprintf("Unable to empty bucket %v: %v", physicalId, purgeErr))
+			}
+			fmt.Printf("Emptied bucket %v: %v object version(s) deleted\n", physicalId, count)
+		case "AWS::ECR::Repository":
+			count, purgeErr := d.purger.deleteImages(ctx, location, physicalId)
+			if purgeErr != nil {
+				return errors.New(fmt.Sprintf("Unable to empty repository %v: %v", physicalId, purgeErr))
+			}
+			fmt.Printf("Emptied repository %v: %v image(s) deleted\n", physicalId, count)
+		}
+	}
+	return nil
+}
+
+// keptResources returns the logical ids of a template whose DeletionPolicy is not Delete, with their policy.
+// Policies that are not a plain value, e.g. Fn::If, count as not Delete.
+func keptResources(template string) (map[string]string, error) {
+	kept := make(map[string]string)
+	var document yaml.Node
+	if parseErr := yaml.Unmarshal([]byte(template), &document); parseErr != nil {
+		return nil, parseErr
+	}
+	if len(document.Content) == 0 {
+		return kept, nil
+	}
+
+	resources := mappingValue(document.Content[0], "Resources")
+	if resources == nil || resources.Kind != yaml.MappingNode {
+		return kept, nil
+	}
+	for i := 0; i+1 < len(resources.Content); i += 2 {
+		policy := mappingValue(resources.Content[i+1], "DeletionPolicy")
+		if policy == nil {
+			continue
+		}
+		if policy.Kind != yaml.ScalarNode {
+			kept[resources.Content[i].Value] = "conditional"
+		} else if policy.Value != "Delete" {
+			kept[resources.Content[i].Value] = policy.Value
+		}
+	}
+	return kept, nil
+}
+
+// addDeletionFlags registers the flags of the purge before, and the retry after, a stack deletion.
+func addDeletionFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("purge", false, "Empty the S3 buckets and ECR repositories of the stack before deleting it")
+	cmd.Flags().Bool("retain-failed", false, "Retry stacks that end in DELETE_FAILED, leaving the resources that failed to delete in place")
+	cmd.Flags().String("retained-report", "retained-resources.yml", "File to write the resources left in place by --retain-failed to")
+}
+
+// writeReport prints the retained resources and writes them to a yaml file, if a path is given.
+// Nothing is written when no resource was retained.
+func (d *stackDeleter) writeReport(reportPath string) error {
+	if len(d.retained) == 0 {
+		return nil
+	}
+
+	for _, resource := range d.retained {
+		fmt.Printf("Retained %v (%v) %v of stack %v (%v/%v)\n", resource.LogicalId, resource.Type, resource.PhysicalId, resource.Stack, resource.Account, resource.Region)
+	}
+	if reportPath == "" {
+		return nil
+	}
+	content, marshalErr := yaml.Marshal(d.retained)
+	if marshalErr != nil {
+		return marshalErr
+	}
+	fmt.Printf("Writing retained resources to: %v\n", reportPath)
+	return ioutil.WriteFile(reportPath, content, 0644)
+}
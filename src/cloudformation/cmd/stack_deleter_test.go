@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
+)
+
+func TestStackDeleterWriteReport(t *testing.T) {
+	// arrange
+	dir, _ := ioutil.TempDir("", "report")
+	reportPath := filepath.Join(dir, "retained.yml")
+	deleter := &stackDeleter{
+		retained: []*retainedResource{
+			&retainedResource{
+				Stack:      "a",
+				Region:     "us-east-1",
+				LogicalId:  "Bucket",
+				Type:       "AWS::S3::Bucket",
+				PhysicalId: "bucket-name",
+				Reason:     "The bucket you tried to delete is not empty",
+			},
+		},
+	}
+
+	// act
+	err := deleter.writeReport(reportPath)
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := ioutil.ReadFile(reportPath)
+	for _, expected := range []string{"stack: a", "logical-id: Bucket", "physical-id: bucket-name", "region: us-east-1"} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Report is missing %#v.\n%v", expected, string(content))
+		}
+	}
+}
+
+func TestStackDeleterWriteReport_NothingRetained(t *testing.T) {
+	// arrange
+	dir, _ := ioutil.TempDir("", "report")
+	reportPath := filepath.Join(dir, "retained.yml")
+	deleter := &stackDeleter{}
+
+	// act
+	err := deleter.writeReport(reportPath)
+
+	// assert
+	if _, statErr := ioutil.ReadFile(reportPath); err != nil || statErr == nil {
+		t.Error("No report should be written when nothing was retained.")
+	}
+}
+
+func TestStackDeleterDeleteStack_Purge(t *testing.T) {
+	// arrange
+	root, nested := testStack("root"), testStack("root-nested")
+	resources := map[string][]*cloudformation.StackResourceSummary{
+		*root.StackId: {
+			&cloudformation.StackResourceSummary{
+				ResourceType:       aws.String("AWS::S3::Bucket"),
+				PhysicalResourceId: aws.String("root-bucket"),
+				ResourceStatus:     aws.String(cloudformation.ResourceStatusCreateComplete),
+			},
+			&cloudformation.StackResourceSummary{
+				ResourceType:       aws.String(nestedStackResourceType),
+				PhysicalResourceId: nested.StackId,
+				ResourceStatus:     aws.String(cloudformation.ResourceStatusCreateComplete),
+			},
+			&cloudformation.StackResourceSummary{
+				ResourceType:       aws.String("AWS::S3::Bucket"),
+				PhysicalResourceId: aws.String("gone-bucket"),
+				ResourceStatus:     aws.String(cloudformation.ResourceStatusDeleteComplete),
+			},
+		},
+		*nested.StackId: {
+			&cloudformation.StackResourceSummary{
+				ResourceType:       aws.String("AWS::ECR::Repository"),
+				PhysicalResourceId: aws.String("nested-repository"),
+				ResourceStatus:     aws.String(cloudformation.ResourceStatusCreateComplete),
+			},
+		},
+	}
+	purger := &mockResourcePurger{}
+	deleted := false
+	deleter := &stackDeleter{
+		cfnManager: &mockCfnManager{
+			listStackResourcesStub: func(stackName *string) ([]*cloudformation.StackResourceSummary, error) {
+				return resources[*stackName], nil
+			},
+			deleteStub: func(stackName *string) error {
+				deleted = len(purger.emptied) == 2
+				return nil
+			},
+		},
+		purger: purger,
+	}
+
+	// act
+	err := deleter.deleteStack(context.Background(), root.StackId)
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(purger.emptied) != 2 || purger.emptied[0] != "root-bucket" || purger.emptied[1] != "nested-repository" {
+		t.Errorf("Buckets and repositories of the stack and its nested stacks should be emptied. %#v", purger.emptied)
+	}
+	if !deleted {
+		t.Error("The stack should be deleted after it was purged.")
+	}
+}
+
+func TestStackDeleterDeleteStack_PurgeSkipsRetained(t *testing.T) {
+	// arrange
+	root := testStack("root")
+	purger := &mockResourcePurger{}
+	deleter := &stackDeleter{
+		cfnManager: &mockCfnManager{
+			getStackTemplateStub: func(stackName *string) (*string, error) {
+				return aws.String(`{"Resources": {
+					"Logs": {"Type": "AWS::S3::Bucket", "DeletionPolicy": "Retain"},
+					"Images": {"Type": "AWS::ECR::Repository", "DeletionPolicy": "Snapshot"},
+					"Scratch": {"Type": "AWS::S3::Bucket", "DeletionPolicy": "Delete"}}}`), nil
+			},
+			listStackResourcesStub: func(stackName *string) ([]*cloudformation.StackResourceSummary, error) {
+				return []*cloudformation.StackResourceSummary{
+					{
+						LogicalResourceId:  aws.String("Logs"),
+						ResourceType:       aws.String("AWS::S3::Bucket"),
+						PhysicalResourceId: aws.String("retained-bucket"),
+						ResourceStatus:     aws.String(cloudformation.ResourceStatusCreateComplete),
+					},
+					{
+						LogicalResourceId:  aws.String("Images"),
+						ResourceType:       aws.String("AWS::ECR::Repository"),
+						PhysicalResourceId: aws.String("snapshot-repository"),
+						ResourceStatus:     aws.String(cloudformation.ResourceStatusCreateComplete),
+					},
+					{
+						LogicalResourceId:  aws.String("Scratch"),
+						ResourceType:       aws.String("AWS::S3::Bucket"),
+						PhysicalResourceId: aws.String("scratch-bucket"),
+						ResourceStatus:     aws.String(cloudformation.ResourceStatusCreateComplete),
+					},
+				}, nil
+			},
+		},
+		purger: purger,
+	}
+
+	// act
+	err := deleter.deleteStack(context.Background(), root.StackId)
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(purger.emptied) != 1 || purger.emptied[0] != "scratch-bucket" {
+		t.Errorf("Only resources deleted with the stack should be emptied. %#v", purger.emptied)
+	}
+}
+
+func TestStackDeleterPurgeStack_SecondRegion(t *testing.T) {
+	// arrange
+	regionApi := &mockCfnAPI{
+		getTemplateStub: func(input *cloudformation.GetTemplateInput) (*cloudformation.GetTemplateOutput, error) {
+			return &cloudformation.GetTemplateOutput{
+				TemplateBody: aws.String(`{"Resources": {"Logs": {"Type": "AWS::S3::Bucket", "DeletionPolicy": "Retain"}}}`),
+			}, nil
+		},
+		listStackResourcesStub: func(input *cloudformation.ListStackResourcesInput) (*cloudformation.ListStackResourcesOutput, error) {
+			return &cloudformation.ListStackResourcesOutput{
+				StackResourceSummaries: []*cloudformation.StackResourceSummary{
+					{
+						LogicalResourceId:  aws.String("Logs"),
+						ResourceType:       aws.String("AWS::S3::Bucket"),
+						PhysicalResourceId: aws.String("retained-bucket"),
+						ResourceStatus:     aws.String(cloudformation.ResourceStatusCreateComplete),
+					},
+					{
+						LogicalResourceId:  aws.String("Scratch"),
+						ResourceType:       aws.String("AWS::S3::Bucket"),
+						PhysicalResourceId: aws.String("scratch-bucket"),
+						ResourceStatus:     aws.String(cloudformation.ResourceStatusCreateComplete),
+					},
+				},
+			}, nil
+		},
+	}
+	purger := &mockResourcePurger{}
+	deleter := &stackDeleter{
+		cfnManager: &cfnManager{
+			cfn: &mockCfnAPI{},
+			regions: &regionClients{
+				sessionRegion: "us-east-1",
+				newClient: func(region string) cloudformationiface.CloudFormationAPI {
+					if region == "eu-west-1" {
+						return regionApi
+					}
+					return &mockCfnAPI{}
+				},
+				clients: make(map[string]cloudformationiface.CloudFormationAPI),
+			},
+		},
+		purger: purger,
+	}
+
+	// act
+	err := deleter.purgeStack(context.Background(), aws.String("arn:aws:cloudformation:eu-west-1:111111111111:stack/a/id"))
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(purger.emptied) != 1 || purger.emptied[0] != "scratch-bucket" {
+		t.Errorf("The template should be read in the region of the stack. %#v", purger.emptied)
+	}
+}
+
+func TestStackDeleterDeleteStack_PurgeFailure(t *testing.T) {
+	// arrange
+	deleted := false
+	deleter := &stackDeleter{
+		cfnManager: &mockCfnManager{
+			listStackResourcesStub: func(stackName *string) ([]*cloudformation.StackResourceSummary, error) {
+				return []*cloudformation.StackResourceSummary{
+					&cloudformation.StackResourceSummary{
+						ResourceType:       aws.String("AWS::S3::Bucket"),
+						PhysicalResourceId: aws.String("bucket"),
+					},
+				}, nil
+			},
+			deleteStub: func(stackName *string) error {
+				deleted = true
+				return nil
+			},
+		},
+		purger: &mockResourcePurger{
+			emptyBucketStub: func(region string, bucket string) (int, error) {
+				return 0, errors.New("AccessDenied")
+			},
+		},
+	}
+
+	// act
+	err := deleter.deleteStack(context.Background(), testStack("a").StackId)
+
+	// assert
+	if err == nil || deleted {
+		t.Error("The stack should not be deleted when it could not be purged.")
+	}
+}
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
+)
+
+const nestedStackResourceType = "AWS::CloudFormation::Stack"
+
+// stackEventTailer prints the events of a stack, and of any nested stacks it discovers, as they occur.
+type stackEventTailer struct {
+	ctx      aws.Context
+	cfn      cloudformationiface.CloudFormationAPI
+	out      io.Writer
+	since    time.Time
+	interval time.Duration
+	stacks   []string
+	seen     map[string]bool
+}
+
+func newStackEventTailer(ctx aws.Context, cfn cloudformationiface.CloudFormationAPI, out io.Writer, stackName string, since time.Time) *stackEventTailer {
+	return &stackEventTailer{
+		ctx:      ctx,
+		cfn:      cfn,
+		out:      out,
+		since:    since,
+		interval: 5 * time.Second,
+		stacks:   []string{stackName},
+		seen:     make(map[string]bool),
+	}
+}
+
+// start polls for events in the background. The returned function stops the polling
+// after one last poll so the final events are not lost.
+func (t *stackEventTailer) start() func() {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(t.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				t.poll()
+				return
+			case <-ticker.C:
+				if err := t.poll(); err != nil {
+					fmt.Fprintf(t.out, "Unable to read stack events: %v\n", err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// poll prints the events that occurred since the last poll, oldest first.
+func (t *stackEventTailer) poll() error {
+	events := make([]*cloudformation.StackEvent, 0)
+	for i := 0; i < len(t.stacks); i++ {
+		stackEvents, err := t.newEvents(t.stacks[i])
+		if err != nil {
+			return err
+		}
+		events = append(events, stackEvents...)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return aws.TimeValue(events[i].Timestamp).Before(aws.TimeValue(events[j].Timestamp))
+	})
+	for _, event := range events {
+		t.seen[aws.StringValue(event.EventId)] = true
+		fmt.Fprintf(t.out, "%v  %v  %v  %v  %v  %v\n",
+			aws.TimeValue(event.Timestamp).Format(time.RFC3339),
+			aws.StringValue(event.StackName),
+			aws.StringValue(event.LogicalResourceId),
+			aws.StringValue(event.ResourceType),
+			aws.StringValue(event.ResourceStatus),
+			aws.StringValue(event.ResourceStatusReason))
+		t.follow(event)
+	}
+
+	return nil
+}
+
+// newEvents returns the events of a stack that are newer than since and not printed yet.
+func (t *stackEventTailer) newEvents(stackName string) ([]*cloudformation.StackEvent, error) {
+	stackEvents, err := stackEventsSince(t.ctx, t.cfn, stackName, t.since)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]*cloudformation.StackEvent, 0, len(stackEvents))
+	for _, event := range stackEvents {
+		if !t.seen[aws.StringValue(event.EventId)] {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// follow starts tailing a nested stack once its resource event shows up in a parent stack.
+func (t *stackEventTailer) follow(event *cloudformation.StackEvent) {
+	if aws.StringValue(event.ResourceType) != nestedStackResourceType {
+		return
+	}
+	nestedStackId := aws.StringValue(event.PhysicalResourceId)
+	if nestedStackId == "" || nestedStackId == aws.StringValue(event.StackId) {
+		return
+	}
+	for _, stack := range t.stacks {
+		if stack == nestedStackId {
+			return
+		}
+	}
+	t.stacks = append(t.stacks, nestedStackId)
+}
+
+// latestEventTime returns when the last event of a stack occurred, by the clock of CloudFormation, to tail the events
+// of the next operation from. The local clock may be off. It falls back to the local time when no event can be read.
+func latestEventTime(ctx aws.Context, cfn cloudformationiface.CloudFormationAPI, stackName string) time.Time {
+	page, err := cfn.DescribeStackEventsWithContext(ctx, &cloudformation.DescribeStackEventsInput{
+		StackName: aws.String(stackName),
+	})
+	if err != nil || len(page.StackEvents) == 0 {
+		return time.Now()
+	}
+	// Events are returned newest first.
+	return aws.TimeValue(page.StackEvents[0].Timestamp)
+}
+
+// stackEventsSince returns the events of a stack that occurred after since, newest first.
+func stackEventsSince(ctx aws.Context, cfn cloudformationiface.CloudFormationAPI, stackName string, since time.Time) ([]*cloudformation.StackEvent, error) {
+	events := make([]*cloudformation.StackEvent, 0)
+	input := &cloudformation.DescribeStackEventsInput{
+		StackName: aws.String(stackName),
+	}
+	for {
+		page, err := cfn.DescribeStackEventsWithContext(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+
+		// Events are returned newest first. Stop paging once we are past the start time.
+		for _, event := range page.StackEvents {
+			if !aws.TimeValue(event.Timestamp).After(since) {
+				return events, nil
+			}
+			events = append(events, event)
+		}
+
+		if page.NextToken == nil {
+			return events, nil
+		}
+		input.NextToken = page.NextToken
+	}
+}
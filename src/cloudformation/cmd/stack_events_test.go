@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+)
+
+func TestStackEventTailerPoll_PrintsNewEventsOnce(t *testing.T) {
+	// arrange
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	events := []*cloudformation.StackEvent{
+		&cloudformation.StackEvent{
+			EventId:           aws.String("2"),
+			StackId:           aws.String("root"),
+			StackName:         aws.String("root"),
+			LogicalResourceId: aws.String("Bucket"),
+			ResourceType:      aws.String("AWS::S3::Bucket"),
+			ResourceStatus:    aws.String(cloudformation.ResourceStatusCreateInProgress),
+			Timestamp:         aws.Time(start.Add(2 * time.Second)),
+		},
+		&cloudformation.StackEvent{
+			EventId:           aws.String("1"),
+			StackId:           aws.String("root"),
+			StackName:         aws.String("root"),
+			LogicalResourceId: aws.String("root"),
+			ResourceType:      aws.String(nestedStackResourceType),
+			ResourceStatus:    aws.String(cloudformation.ResourceStatusUpdateInProgress),
+			Timestamp:         aws.Time(start.Add(time.Second)),
+		},
+		&cloudformation.StackEvent{
+			EventId:   aws.String("0"),
+			Timestamp: aws.Time(start.Add(-time.Second)),
+		},
+	}
+	api := &mockCfnAPI{
+		describeStackEventsStub: func(input *cloudformation.DescribeStackEventsInput) (*cloudformation.DescribeStackEventsOutput, error) {
+			return &cloudformation.DescribeStackEventsOutput{StackEvents: events}, nil
+		},
+	}
+	out := &bytes.Buffer{}
+	tailer := newStackEventTailer(context.Background(), api, out, "root", start)
+
+	// act
+	tailer.poll()
+	tailer.poll()
+
+	// assert
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 events to be printed.\n%v", out.String())
+	}
+	if !strings.Contains(lines[0], "UPDATE_IN_PROGRESS") || !strings.Contains(lines[1], "Bucket") {
+		t.Errorf("Events should be printed oldest first.\n%v", out.String())
+	}
+	if len(tailer.stacks) != 1 {
+		t.Error("The root stack event should not be followed as a nested stack.")
+	}
+}
+
+func TestLatestEventTime_TailsFromLastEvent(t *testing.T) {
+	// arrange
+	last := time.Now().Add(time.Hour) // CloudFormation's clock ahead of the local one
+	events := []*cloudformation.StackEvent{
+		&cloudformation.StackEvent{
+			EventId:        aws.String("1"),
+			StackName:      aws.String("root"),
+			ResourceStatus: aws.String(cloudformation.ResourceStatusUpdateComplete),
+			Timestamp:      aws.Time(last),
+		},
+		&cloudformation.StackEvent{
+			EventId:   aws.String("0"),
+			Timestamp: aws.Time(last.Add(-time.Minute)),
+		},
+	}
+	api := &mockCfnAPI{
+		describeStackEventsStub: func(input *cloudformation.DescribeStackEventsInput) (*cloudformation.DescribeStackEventsOutput, error) {
+			return &cloudformation.DescribeStackEventsOutput{StackEvents: events}, nil
+		},
+	}
+	out := &bytes.Buffer{}
+
+	// act
+	since := latestEventTime(context.Background(), api, "root")
+	events = append([]*cloudformation.StackEvent{
+		&cloudformation.StackEvent{
+			EventId:        aws.String("2"),
+			StackName:      aws.String("root"),
+			ResourceStatus: aws.String(cloudformation.ResourceStatusUpdateInProgress),
+			Timestamp:      aws.Time(last.Add(time.Second)),
+		},
+	}, events...)
+	newStackEventTailer(context.Background(), api, out, "root", since).poll()
+
+	// assert
+	if !since.Equal(last) {
+		t.Errorf("The cutoff should be the time of the last event. %v", since)
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 1 || !strings.Contains(lines[0], "UPDATE_IN_PROGRESS") {
+		t.Errorf("Only the events of the new operation should be printed.\n%v", out.String())
+	}
+}
+
+func TestStackEventTailerPoll_FollowsNestedStacks(t *testing.T) {
+	// arrange
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	api := &mockCfnAPI{
+		describeStackEventsStub: func(input *cloudformation.DescribeStackEventsInput) (*cloudformation.DescribeStackEventsOutput, error) {
+			if *input.StackName == "nested" {
+				return &cloudformation.DescribeStackEventsOutput{
+					StackEvents: []*cloudformation.StackEvent{
+						&cloudformation.StackEvent{
+							EventId:           aws.String("n1"),
+							StackId:           aws.String("nested"),
+							StackName:         aws.String("nested"),
+							LogicalResourceId: aws.String("Queue"),
+							ResourceType:      aws.String("AWS::SQS::Queue"),
+							ResourceStatus:    aws.String(cloudformation.ResourceStatusCreateComplete),
+							Timestamp:         aws.Time(start.Add(2 * time.Second)),
+						},
+					},
+				}, nil
+			}
+			return &cloudformation.DescribeStackEventsOutput{
+				StackEvents: []*cloudformation.StackEvent{
+					&cloudformation.StackEvent{
+						EventId:            aws.String("r1"),
+						StackId:            aws.String("root"),
+						StackName:          aws.String("root"),
+						LogicalResourceId:  aws.String("Child"),
+						PhysicalResourceId: aws.String("nested"),
+						ResourceType:       aws.String(nestedStackResourceType),
+						ResourceStatus:     aws.String(cloudformation.ResourceStatusCreateInProgress),
+						Timestamp:          aws.Time(start.Add(time.Second)),
+					},
+				},
+			}, nil
+		},
+	}
+	out := &bytes.Buffer{}
+	tailer := newStackEventTailer(context.Background(), api, out, "root", start)
+
+	// act
+	tailer.poll()
+	tailer.poll()
+
+	// assert
+	if len(tailer.stacks) != 2 {
+		t.Error("Nested stack should be followed.")
+	}
+	if !strings.Contains(out.String(), "Queue") {
+		t.Errorf("Nested stack events should be printed.\n%v", out.String())
+	}
+}
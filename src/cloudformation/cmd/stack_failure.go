@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
+)
+
+// stackFailureError reports the resource that caused a stack operation to fail.
+type stackFailureError struct {
+	stackName   string
+	stackStatus string
+	rootCause   *cloudformation.StackEvent
+}
+
+func (e *stackFailureError) Error() string {
+	if e.rootCause == nil {
+		return fmt.Sprintf("Stack %v ended in %v. No failed resource found.", e.stackName, e.stackStatus)
+	}
+	return fmt.Sprintf("Stack %v ended in %v. Root cause: %v (%v) in stack %v is %v: %v",
+		e.stackName,
+		e.stackStatus,
+		aws.StringValue(e.rootCause.LogicalResourceId),
+		aws.StringValue(e.rootCause.ResourceType),
+		aws.StringValue(e.rootCause.StackName),
+		aws.StringValue(e.rootCause.ResourceStatus),
+		aws.StringValue(e.rootCause.ResourceStatusReason))
+}
+
+func (e *stackFailureError) ExitCode() int {
+	switch e.stackStatus {
+	case cloudformation.StackStatusRollbackComplete, cloudformation.StackStatusUpdateRollbackComplete, cloudformation.StackStatusImportRollbackComplete:
+		return exitRolledBack
+	case cloudformation.StackStatusRollbackFailed, cloudformation.StackStatusUpdateRollbackFailed, cloudformation.StackStatusImportRollbackFailed:
+		return exitRollbackFailed
+	default:
+		return exitError
+	}
+}
+
+var failedStackStatuses = map[string]bool{
+	cloudformation.StackStatusCreateFailed:           true,
+	cloudformation.StackStatusDeleteFailed:           true,
+	cloudformation.StackStatusRollbackComplete:       true,
+	cloudformation.StackStatusRollbackFailed:         true,
+	cloudformation.StackStatusUpdateFailed:           true,
+	cloudformation.StackStatusUpdateRollbackComplete: true,
+	cloudformation.StackStatusUpdateRollbackFailed:   true,
+	cloudformation.StackStatusImportRollbackComplete: true,
+	cloudformation.StackStatusImportRollbackFailed:   true,
+}
+
+// explainStackFailure turns a failed stack operation into a stackFailureError pointing at the first failed resource.
+// The original error is returned when the stack did not end in a failed state.
+func explainStackFailure(ctx aws.Context, cfn cloudformationiface.CloudFormationAPI, stackName string, since time.Time, waitErr error) error {
+	stacks, err := cfn.DescribeStacksWithContext(ctx, &cloudformation.DescribeStacksInput{
+		StackName: aws.String(stackName),
+	})
+	if err != nil || len(stacks.Stacks) != 1 {
+		return waitErr
+	}
+	stackStatus := aws.StringValue(stacks.Stacks[0].StackStatus)
+	if !failedStackStatuses[stackStatus] {
+		return waitErr
+	}
+
+	rootCause, err := findRootCause(ctx, cfn, stackName, since)
+	if err != nil {
+		return waitErr
+	}
+
+	return &stackFailureError{
+		stackName:   aws.StringValue(stacks.Stacks[0].StackName),
+		stackStatus: stackStatus,
+		rootCause:   rootCause,
+	}
+}
+
+// findRootCause returns the earliest *_FAILED resource event since the given time.
+// Failed nested stacks are searched recursively for the resource that failed inside them.
+func findRootCause(ctx aws.Context, cfn cloudformationiface.CloudFormationAPI, stackName string, since time.Time) (*cloudformation.StackEvent, error) {
+	events, err := stackEventsSince(ctx, cfn, stackName, since)
+	if err != nil {
+		return nil, err
+	}
+
+	// Events are newest first. Walk backwards to find the earliest failure.
+	for i := len(events) - 1; i >= 0; i-- {
+		event := events[i]
+		if !strings.HasSuffix(aws.StringValue(event.ResourceStatus), "_FAILED") {
+			continue
+		}
+		physicalId := aws.StringValue(event.PhysicalResourceId)
+		isNestedStack := aws.StringValue(event.ResourceType) == nestedStackResourceType
+		if isNestedStack && physicalId == aws.StringValue(event.StackId) {
+			// Status of the stack itself rather than one of its resources.
+			continue
+		}
+		if isNestedStack && physicalId != "" {
+			if nestedCause, nestedErr := findRootCause(ctx, cfn, physicalId, since); nestedErr == nil && nestedCause != nil {
+				return nestedCause, nil
+			}
+		}
+		return event, nil
+	}
+
+	return nil, nil
+}
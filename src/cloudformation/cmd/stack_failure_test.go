@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+)
+
+func TestExplainStackFailure_FindsNestedRootCause(t *testing.T) {
+	// arrange
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	api := &mockCfnAPI{
+		describeStacksStub: func(input *cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error) {
+			return &cloudformation.DescribeStacksOutput{
+				Stacks: []*cloudformation.Stack{
+					&cloudformation.Stack{
+						StackName:   aws.String("root"),
+						StackStatus: aws.String(cloudformation.StackStatusUpdateRollbackComplete),
+					},
+				},
+			}, nil
+		},
+		describeStackEventsStub: func(input *cloudformation.DescribeStackEventsInput) (*cloudformation.DescribeStackEventsOutput, error) {
+			if *input.StackName == "nested" {
+				return &cloudformation.DescribeStackEventsOutput{
+					StackEvents: []*cloudformation.StackEvent{
+						&cloudformation.StackEvent{
+							StackId:              aws.String("nested"),
+							StackName:            aws.String("nested"),
+							LogicalResourceId:    aws.String("Queue"),
+							ResourceType:         aws.String("AWS::SQS::Queue"),
+							ResourceStatus:       aws.String(cloudformation.ResourceStatusUpdateFailed),
+							ResourceStatusReason: aws.String("queue is broken"),
+							Timestamp:            aws.Time(start.Add(2 * time.Second)),
+						},
+					},
+				}, nil
+			}
+			return &cloudformation.DescribeStackEventsOutput{
+				StackEvents: []*cloudformation.StackEvent{
+					&cloudformation.StackEvent{
+						StackId:              aws.String("root"),
+						StackName:            aws.String("root"),
+						LogicalResourceId:    aws.String("Other"),
+						ResourceType:         aws.String("AWS::S3::Bucket"),
+						ResourceStatus:       aws.String(cloudformation.ResourceStatusUpdateFailed),
+						ResourceStatusReason: aws.String("Resource update cancelled"),
+						Timestamp:            aws.Time(start.Add(4 * time.Second)),
+					},
+					&cloudformation.StackEvent{
+						StackId:              aws.String("root"),
+						StackName:            aws.String("root"),
+						LogicalResourceId:    aws.String("Child"),
+						PhysicalResourceId:   aws.String("nested"),
+						ResourceType:         aws.String(nestedStackResourceType),
+						ResourceStatus:       aws.String(cloudformation.ResourceStatusUpdateFailed),
+						ResourceStatusReason: aws.String("Embedded stack was not successfully updated"),
+						Timestamp:            aws.Time(start.Add(3 * time.Second)),
+					},
+				},
+			}, nil
+		},
+	}
+
+	// act
+	err := explainStackFailure(context.Background(), api, "root", start, errors.New("waiter failed"))
+
+	// assert
+	failure, ok := err.(*stackFailureError)
+	if !ok {
+		t.Fatalf("Expected a stack failure error, got %#v", err)
+	}
+	if !strings.Contains(failure.Error(), "queue is broken") {
+		t.Errorf("Root cause should come from the nested stack.\n%v", failure.Error())
+	}
+	if ExitCode(err) != exitRolledBack {
+		t.Error("A clean rollback should have its own exit code.")
+	}
+}
+
+func TestExplainStackFailure_KeepsErrorForHealthyStack(t *testing.T) {
+	// arrange
+	api := &mockCfnAPI{
+		describeStacksStub: func(input *cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error) {
+			return &cloudformation.DescribeStacksOutput{
+				Stacks: []*cloudformation.Stack{
+					&cloudformation.Stack{StackStatus: aws.String(cloudformation.StackStatusUpdateInProgress)},
+				},
+			}, nil
+		},
+	}
+	waitErr := errors.New("waiter failed")
+
+	// act
+	err := explainStackFailure(context.Background(), api, "root", time.Now(), waitErr)
+
+	// assert
+	if err != waitErr {
+		t.Error("The original error should be kept when the stack has not failed.")
+	}
+}
+
+func TestExplainStackFailure_ImportRollback(t *testing.T) {
+	cases := map[string]int{
+		cloudformation.StackStatusImportRollbackComplete: exitRolledBack,
+		cloudformation.StackStatusImportRollbackFailed:   exitRollbackFailed,
+	}
+
+	for status, exitCode := range cases {
+		// arrange
+		start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		stackStatus := status
+		api := &mockCfnAPI{
+			describeStacksStub: func(input *cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error) {
+				return &cloudformation.DescribeStacksOutput{
+					Stacks: []*cloudformation.Stack{
+						&cloudformation.Stack{StackName: aws.String("root"), StackStatus: aws.String(stackStatus)},
+					},
+				}, nil
+			},
+			describeStackEventsStub: func(input *cloudformation.DescribeStackEventsInput) (*cloudformation.DescribeStackEventsOutput, error) {
+				return &cloudformation.DescribeStackEventsOutput{
+					StackEvents: []*cloudformation.StackEvent{
+						&cloudformation.StackEvent{
+							StackId:              aws.String("root"),
+							StackName:            aws.String("root"),
+							LogicalResourceId:    aws.String("Table"),
+							ResourceType:         aws.String("AWS::DynamoDB::Table"),
+							ResourceStatus:       aws.String(cloudformation.ResourceStatusImportFailed),
+							ResourceStatusReason: aws.String("table not found"),
+							Timestamp:            aws.Time(start.Add(time.Second)),
+						},
+					},
+				}, nil
+			},
+		}
+
+		// act
+		err := explainStackFailure(context.Background(), api, "root", start, errors.New("waiter failed"))
+
+		// assert
+		if _, ok := err.(*stackFailureError); !ok {
+			t.Errorf("%v: expected a stack failure error, got %#v", status, err)
+		}
+		if ExitCode(err) != exitCode {
+			t.Errorf("%v: expected exit code %v, got %v", status, exitCode, ExitCode(err))
+		}
+	}
+}
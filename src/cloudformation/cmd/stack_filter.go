@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// stackFilter selects the stacks a bulk command acts on. Empty criteria match every stack.
+type stackFilter struct {
+	regions          []string
+	names            []string // glob patterns
+	nameRegex        *regexp.Regexp
+	tags             map[string]string // key to value, an empty value matches any value
+	statuses         []string
+	createdOlderThan time.Duration
+	updatedOlderThan time.Duration
+	excludes         []string // glob patterns read from the exclude file
+	now              time.Time
+}
+
+// addStackFilterFlags registers the stack selector flags on a command.
+func addStackFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSlice("name", nil, "Only select stacks whose name matches one of these glob patterns")
+	cmd.Flags().String("name-regex", "", "Only select stacks whose name matches this regular expression")
+	cmd.Flags().StringSlice("match-tag", nil, "Only select stacks with all of these tags, as key=value or key for any value")
+	cmd.Flags().StringSlice("status", nil, "Only select stacks in one of these statuses")
+	cmd.Flags().Duration("created-older-than", 0, "Only select stacks created longer ago than this, e.g. 72h")
+	cmd.Flags().Duration("updated-older-than", 0, "Only select stacks last updated (or created) longer ago than this, e.g. 72h")
+	cmd.Flags().String("exclude-file", "", "File with stack names or glob patterns to never select, one per line")
+}
+
+// readStackFilter builds a stackFilter from the selector flags. Invalid values are returned as errstrings.
+// Regions come from the persistent regions flag, which also limits the regions listed.
+func readStackFilter(localViper *viper.Viper) (*stackFilter, []string) {
+	var errstrings []string
+	filter := &stackFilter{
+		regions:          localViper.GetStringSlice("regions"),
+		names:            localViper.GetStringSlice("name"),
+		statuses:         localViper.GetStringSlice("status"),
+		createdOlderThan: localViper.GetDuration("created-older-than"),
+		updatedOlderThan: localViper.GetDuration("updated-older-than"),
+		tags:             make(map[string]string),
+		now:              time.Now(),
+	}
+
+	for _, pattern := range filter.names {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errstrings = append(errstrings, fmt.Sprintf("Invalid name pattern %#v: %v", pattern, err))
+		}
+	}
+
+	if expr := localViper.GetString("name-regex"); expr != "" {
+		nameRegex, err := regexp.Compile(expr)
+		if err != nil {
+			errstrings = append(errstrings, fmt.Sprintf("Invalid name-regex %#v: %v", expr, err))
+		}
+		filter.nameRegex = nameRegex
+	}
+
+	for _, selector := range localViper.GetStringSlice("match-tag") {
+		keyValue := strings.SplitN(selector, "=", 2)
+		if keyValue[0] == "" {
+			errstrings = append(errstrings, fmt.Sprintf("Invalid match-tag %#v.", selector))
+			continue
+		}
+		if len(keyValue) == 2 {
+			filter.tags[keyValue[0]] = keyValue[1]
+		} else {
+			filter.tags[keyValue[0]] = ""
+		}
+	}
+
+	if excludeFile := localViper.GetString("exclude-file"); excludeFile != "" {
+		excludes, err := readExcludeFile(excludeFile)
+		if err != nil {
+			errstrings = append(errstrings, fmt.Sprintf("Unable to read exclude-file: %v", err))
+		}
+		filter.excludes = excludes
+	}
+
+	return filter, errstrings
+}
+
+// readExcludeFile returns the patterns of an exclude file. Blank lines and # comments are ignored.
+func readExcludeFile(excludeFile string) ([]string, error) {
+	file, err := os.Open(excludeFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	excludes := make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, matchErr := path.Match(line, ""); matchErr != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid pattern %#v: %v", line, matchErr))
+		}
+		excludes = append(excludes, line)
+	}
+	return excludes, scanner.Err()
+}
+
+// match returns an empty string when the stack is selected, otherwise the reason it was filtered out.
+func (f *stackFilter) match(stack *cloudformation.Stack) string {
+	stackName := aws.StringValue(stack.StackName)
+
+	if matchesAny(f.excludes, stackName) {
+		return "excluded"
+	}
+
+	if len(f.regions) > 0 {
+		region := locateStack(stack.StackId).region
+		if !contains(f.regions, region) {
+			return "region " + orDash(region)
+		}
+	}
+
+	if len(f.names) > 0 && !matchesAny(f.names, stackName) {
+		return "name"
+	}
+	if f.nameRegex != nil && !f.nameRegex.MatchString(stackName) {
+		return "name-regex"
+	}
+
+	if len(f.tags) > 0 {
+		stackTags := make(map[string]string)
+		for _, tag := range stack.Tags {
+			stackTags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+		}
+		for key, value := range f.tags {
+			stackValue, exist := stackTags[key]
+			if !exist || (value != "" && value != stackValue) {
+				return "tag " + key
+			}
+		}
+	}
+
+	if len(f.statuses) > 0 && !contains(f.statuses, aws.StringValue(stack.StackStatus)) {
+		return "status " + aws.StringValue(stack.StackStatus)
+	}
+
+	created := aws.TimeValue(stack.CreationTime)
+	if f.createdOlderThan > 0 && f.now.Sub(created) < f.createdOlderThan {
+		return "created " + created.Format(time.RFC3339)
+	}
+	updated := created
+	if stack.LastUpdatedTime != nil {
+		updated = aws.TimeValue(stack.LastUpdatedTime)
+	}
+	if f.updatedOlderThan > 0 && f.now.Sub(updated) < f.updatedOlderThan {
+		return "updated " + updated.Format(time.RFC3339)
+	}
+
+	return ""
+}
+
+// selective reports whether a selector narrows the stacks down. Excludes alone don't.
+func (f *stackFilter) selective() bool {
+	return len(f.regions) > 0 || len(f.names) > 0 || f.nameRegex != nil || len(f.tags) > 0 || len(f.statuses) > 0 ||
+		f.createdOlderThan > 0 || f.updatedOlderThan > 0
+}
+
+func matchesAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, value); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
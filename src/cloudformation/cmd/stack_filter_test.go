@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/spf13/viper"
+)
+
+func TestStackFilterMatch(t *testing.T) {
+	// arrange
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	stack := &cloudformation.Stack{
+		StackName:       aws.String("sandbox-api"),
+		StackId:         aws.String("arn:aws:cloudformation:us-east-1:account-id:stack/sandbox-api"),
+		StackStatus:     aws.String(cloudformation.StackStatusUpdateComplete),
+		CreationTime:    aws.Time(now.Add(-30 * 24 * time.Hour)),
+		LastUpdatedTime: aws.Time(now.Add(-time.Hour)),
+		Tags: []*cloudformation.Tag{
+			&cloudformation.Tag{Key: aws.String("team"), Value: aws.String("core")},
+		},
+	}
+	cases := []struct {
+		filter   *stackFilter
+		selected bool
+	}{
+		{&stackFilter{}, true},
+		{&stackFilter{regions: []string{"us-east-1"}}, true},
+		{&stackFilter{regions: []string{"eu-west-1"}}, false},
+		{&stackFilter{names: []string{"sandbox-*"}}, true},
+		{&stackFilter{names: []string{"prod-*"}}, false},
+		{&stackFilter{tags: map[string]string{"team": ""}}, true},
+		{&stackFilter{tags: map[string]string{"team": "web"}}, false},
+		{&stackFilter{statuses: []string{cloudformation.StackStatusRollbackComplete}}, false},
+		{&stackFilter{createdOlderThan: 7 * 24 * time.Hour, now: now}, true},
+		{&stackFilter{updatedOlderThan: 7 * 24 * time.Hour, now: now}, false},
+		{&stackFilter{excludes: []string{"sandbox-api"}}, false},
+	}
+
+	for i, c := range cases {
+		// act
+		reason := c.filter.match(stack)
+
+		// assert
+		if (reason == "") != c.selected {
+			t.Errorf("Case %v: expected selected %v, got reason %#v", i, c.selected, reason)
+		}
+	}
+}
+
+func TestReadStackFilter(t *testing.T) {
+	// arrange
+	dir, _ := ioutil.TempDir("", "filter")
+	excludeFile := filepath.Join(dir, "exclude")
+	ioutil.WriteFile(excludeFile, []byte("# keep these\nshared-*\n\nbilling\n"), 0644)
+	localViper := viper.New()
+	localViper.Set("match-tag", []string{"team=core", "owner"})
+	localViper.Set("name-regex", "^sandbox-")
+	localViper.Set("exclude-file", excludeFile)
+
+	// act
+	filter, errstrings := readStackFilter(localViper)
+
+	// assert
+	if len(errstrings) != 0 {
+		t.Errorf("Unexpected errors: %v", errstrings)
+	}
+	if filter.tags["team"] != "core" || filter.tags["owner"] != "" || len(filter.tags) != 2 {
+		t.Errorf("Tag selectors were not parsed. %#v", filter.tags)
+	}
+	if len(filter.excludes) != 2 || filter.excludes[0] != "shared-*" {
+		t.Errorf("Exclude file was not parsed. %#v", filter.excludes)
+	}
+	if filter.nameRegex == nil || !filter.nameRegex.MatchString("sandbox-a") {
+		t.Error("Name regex was not compiled.")
+	}
+}
+
+func TestReadStackFilter_Invalid(t *testing.T) {
+	// arrange
+	localViper := viper.New()
+	localViper.Set("name-regex", "(")
+	localViper.Set("match-tag", []string{"=value"})
+	localViper.Set("exclude-file", "/does/not/exist")
+
+	// act
+	_, errstrings := readStackFilter(localViper)
+
+	// assert
+	if len(errstrings) != 3 {
+		t.Errorf("Expected 3 validation errors. %v", errstrings)
+	}
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// apiRequestsPerSecond is how many CloudFormation calls are made per region and second at most.
+// Bulk commands call every stack of every region, which quickly runs into the account's api rate limit otherwise.
+const apiRequestsPerSecond = 5
+
+// throttlingRetryer retries throttled calls with a backoff for longer than the sdk does by default.
+var throttlingRetryer = client.DefaultRetryer{
+	NumMaxRetries:    10,
+	MinRetryDelay:    100 * time.Millisecond,
+	MaxRetryDelay:    5 * time.Second,
+	MinThrottleDelay: 500 * time.Millisecond,
+	MaxThrottleDelay: 30 * time.Second,
+}
+
+// rateLimiter spaces calls out evenly. It is safe for concurrent use.
+type rateLimiter struct {
+	mutex    sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(perSecond int) *rateLimiter {
+	return &rateLimiter{
+		interval: time.Second / time.Duration(perSecond),
+	}
+}
+
+// wait blocks until the next call is allowed or the context is done.
+func (l *rateLimiter) wait(ctx aws.Context) error {
+	l.mutex.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mutex.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	return aws.SleepWithContext(ctx, delay)
+}
+
+// throttle makes every attempt of the requests of a client, retries included, wait for the limiter.
+func throttle(handlers *request.Handlers, limiter *rateLimiter) {
+	handlers.Sign.PushFront(func(r *request.Request) {
+		if err := limiter.wait(r.Context()); err != nil {
+			r.Error = err
+		}
+	})
+}
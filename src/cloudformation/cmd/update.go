@@ -4,55 +4,54 @@ import (
 	"errors"
 	"github.com/aws/aws-sdk-go/service/cloudformation"
 	"github.com/spf13/cobra"
-	"io/ioutil"
 	"strings"
 )
 
 type updateCmd struct {
-	target       string
-	params       map[string]string
-	tags         map[string]string
-	templatePath string
-	cm           *CommandManagement
-	cmd          *cobra.Command
+	target         string
+	params         map[string]string
+	tags           map[string]string
+	templatePath   string
+	templateURL    string
+	templateBucket string
+	artifactBucket string
+	options        *changeSetOptions
+	cm             *CommandManagement
+	cmd            *cobra.Command
 }
 
 func (uc *updateCmd) runE(cmd *cobra.Command, args []string) error {
 
 	cfnManager := uc.cm.cfnManager
+	ctx := uc.cm.context()
 
 	// Get template first.
-	var templateString string
-	if len(uc.templatePath) > 0 {
-		buffer, templateReadErr := ioutil.ReadFile(uc.templatePath)
-		if templateReadErr != nil {
-			return templateReadErr
-		} else {
-			templateString = string(buffer)
-		}
-	} else {
-		stackTemplate, stackTemplateErr := cfnManager.getStackTemplate(&uc.target)
-		if stackTemplateErr != nil {
-			return stackTemplateErr
-		}
-		templateString = *stackTemplate
+	template, templateErr := uc.cm.loadTemplate(ctx, &templateSource{
+		path:           uc.templatePath,
+		url:            uc.templateURL,
+		bucket:         uc.templateBucket,
+		artifactBucket: uc.artifactBucket,
+	}, &uc.target, true)
+	if templateErr != nil {
+		return templateErr
 	}
 
 	// Parameters
-	stackParams, spErr := uc.cm.filterParameters(&templateString, &uc.params, true)
+	stackParams, spErr := uc.cm.filterParameters(ctx, template, &uc.params, true)
 	if spErr != nil {
 		return spErr
 	}
 
 	// Override tags
-	stack, stackErr := cfnManager.getStack(&uc.target)
+	stack, stackErr := cfnManager.getStack(ctx, &uc.target)
 	if stackErr != nil {
 		return stackErr
 	}
 
 	stackTags := uc.cm.mergeTags(stack.Tags, &uc.tags)
 
-	return uc.cm.createAndExecute(&uc.target, stackParams, stackTags, &templateString, cloudformation.ChangeSetTypeUpdate)
+	_, executeErr := uc.cm.createAndExecute(ctx, &uc.target, stackParams, stackTags, template, cloudformation.ChangeSetTypeUpdate, uc.options)
+	return executeErr
 }
 
 func (uc *updateCmd) preRunE(cmd *cobra.Command, args []string) error {
@@ -62,15 +61,25 @@ func (uc *updateCmd) preRunE(cmd *cobra.Command, args []string) error {
 	uc.params = localViper.GetStringMapString("param")
 	uc.tags = localViper.GetStringMapString("tag")
 	uc.templatePath = localViper.GetString("template-path")
+	uc.templateURL = localViper.GetString("template-url")
+	uc.templateBucket = localViper.GetString("template-bucket")
+	uc.artifactBucket = localViper.GetString("artifact-bucket")
+	options, errstrings := readChangeSetOptions(localViper)
+	uc.options = options
 
 	// parameter validations
-	var errstrings []string
 	if uc.target == "" {
 		errstrings = append(errstrings, "Please specify target stack to update.")
 	}
-	if len(uc.params) == 0 && len(uc.tags) == 0 && uc.templatePath == "" {
+	if len(uc.params) == 0 && len(uc.tags) == 0 && uc.templatePath == "" && uc.templateURL == "" && options.empty() {
 		errstrings = append(errstrings, "Nothing specified to update.")
 	}
+	if uc.templatePath != "" && uc.templateURL != "" {
+		errstrings = append(errstrings, "Please specify either template-path or template-url, not both.")
+	}
+	if uc.artifactBucket != "" && uc.templatePath == "" {
+		errstrings = append(errstrings, "Please specify template-path to package artifacts from.")
+	}
 
 	if len(errstrings) > 0 {
 		return errors.New(strings.Join(errstrings, "\n"))
@@ -99,6 +108,10 @@ func (cm *CommandManagement) initUpdateCmd() {
 	cmd.Flags().StringToStringP("param", "p", nil, "Parameters to override")
 	cmd.Flags().StringToStringP("tag", "g", nil, "Parameters to override")
 	cmd.Flags().String("template-path", "", "Parameters to override")
+	cmd.Flags().String("template-url", "", "S3 url of an already hosted template to use instead of template-path")
+	cmd.Flags().String("template-bucket", "", "S3 bucket to stage the template in. Required for templates over 51200 bytes")
+	cmd.Flags().String("artifact-bucket", "", "S3 bucket to upload local artifacts referenced by the template to")
+	addChangeSetFlags(cmd)
 
 	// wire methods.
 	cmd.PreRunE = ucmd.preRunE
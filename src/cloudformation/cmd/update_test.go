@@ -84,11 +84,11 @@ func TestUpdateCmdRunE_SuccessOverridingParam(t *testing.T) {
 				},
 			}, nil
 		},
-		createChangeSetStub: func(stackName *string, params []*cloudformation.Parameter, tags []*cloudformation.Tag, templateBody *string, changeSetType string) (*cloudformation.CreateChangeSetOutput, error) {
+		createChangeSetStub: func(stackName *string, params []*cloudformation.Parameter, tags []*cloudformation.Tag, template *cfnTemplate, changeSetType string) (*cloudformation.CreateChangeSetOutput, error) {
 			resultParams = params
 			return &cloudformation.CreateChangeSetOutput{}, nil
 		},
-		getTemplateSummaryStub: func(templateBody *string) (*cloudformation.GetTemplateSummaryOutput, error) {
+		getTemplateSummaryStub: func(template *cfnTemplate) (*cloudformation.GetTemplateSummaryOutput, error) {
 			return &cloudformation.GetTemplateSummaryOutput{
 				Parameters: []*cloudformation.ParameterDeclaration{
 					&cloudformation.ParameterDeclaration{
@@ -165,7 +165,7 @@ func TestUpdateCmdRunE_SuccessOverridingTag(t *testing.T) {
 				},
 			}, nil
 		},
-		createChangeSetStub: func(stackName *string, params []*cloudformation.Parameter, tags []*cloudformation.Tag, templateBody *string, changeSetType string) (*cloudformation.CreateChangeSetOutput, error) {
+		createChangeSetStub: func(stackName *string, params []*cloudformation.Parameter, tags []*cloudformation.Tag, template *cfnTemplate, changeSetType string) (*cloudformation.CreateChangeSetOutput, error) {
 			resultTags = tags
 			return &cloudformation.CreateChangeSetOutput{}, nil
 		},
@@ -230,3 +230,28 @@ func TestUpdateCmdRunE_StackNotExist(t *testing.T) {
 		t.Error("Should fail when error returned from getStack")
 	}
 }
+
+func TestUpdateCmdRunE_ChangeSetOptions(t *testing.T) {
+
+	// arrange
+	mockCfnManager := &mockCfnManager{}
+	options := &changeSetOptions{roleArn: aws.String("arn:aws:iam::111111111111:role/cfn-deploy")}
+	ucmd := &updateCmd{
+		cm: &CommandManagement{
+			cfnManager: mockCfnManager,
+			config:     &config{mode: noninteractive},
+		},
+		options: options,
+	}
+
+	// act
+	err := ucmd.runE(nil, nil)
+
+	// assert
+	if err != nil {
+		t.Error(err)
+	}
+	if mockCfnManager.changeSetOptions != options {
+		t.Error("The change set options should be passed on to the change set.")
+	}
+}
@@ -2,8 +2,13 @@ package main
 
 import (
 	"aws-machete/src/cloudformation/cmd"
+	"os"
 )
 
 func main() {
-	cmd.CommandManagerInstance.Execute()
+	os.Exit(run())
+}
+
+func run() int {
+	return cmd.ExitCode(cmd.CommandManagerInstance.Execute())
 }
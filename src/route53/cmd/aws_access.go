@@ -2,7 +2,6 @@ package cmd
 
 import (
 	//"fmt"
-	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/route53"
 	"github.com/aws/aws-sdk-go/service/route53/route53iface"
@@ -16,9 +15,7 @@ type route53Manager struct {
 	route53client route53iface.Route53API
 }
 
-func newRoute53client() route53Management {
-	var sess = session.Must(session.NewSession(&aws.Config{}))
-
+func newRoute53client(sess *session.Session) route53Management {
 	var result route53Management = &route53Manager{
 		route53client: route53.New(sess)}
 
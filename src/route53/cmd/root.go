@@ -1,9 +1,11 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
+	"aws-machete/src/awssession"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
@@ -17,9 +19,8 @@ var rootCmdLong = `Route53 cli utility that can can do more sophisticated action
 
 func initRootCmd() *CommandManagement {
 	cm := &CommandManagement{
-		config:         &config{},
-		route53Manager: newRoute53client(),
-		viper:          viper.New(),
+		config: &config{},
+		viper:  viper.New(),
 	}
 	cm.root = &cobra.Command{
 		Use:   "route53",
@@ -48,8 +49,19 @@ func initRootCmd() *CommandManagement {
 				}
 			}
 
+			// The aws clients are built once the session flags are known.
+			options, errstrings := awssession.ReadOptions(cm.viper)
+			if len(errstrings) > 0 {
+				return errors.New(strings.Join(errstrings, "\n"))
+			}
+			sess, sessErr := awssession.New(options)
+			if sessErr != nil {
+				return sessErr
+			}
+			cm.route53Manager = newRoute53client(sess)
+
 			config := cm.config
-			config.timeout = cm.viper.GetInt("timeout")
+			config.timeout = cm.viper.GetInt("wait")
 			modeString := cm.viper.GetString("mode")
 			fmt.Printf("Command execution mode: %v\n", modeString)
 			config.mode = ParseMode(modeString)
@@ -61,6 +73,8 @@ func initRootCmd() *CommandManagement {
 	cm.root.PersistentFlags().StringP("mode", "m", "interactive", "Modes of command execution. Valid options are: noninteractive, changesetonly, dry, interactive.")
 	cm.root.PersistentFlags().IntP("wait", "w", -1, "Time out in seconds to wait for the operation to complete. -1 means wait forever.")
 
+	awssession.AddFlags(cm.root.PersistentFlags())
+
 	// viper flags.
 	cm.root.PersistentFlags().StringP("config-path", "c", "", "Config file to supply flags / parameters with.")
 	cm.root.PersistentFlags().String("config-format", "yaml", "Format of the configuration file.")